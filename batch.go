@@ -0,0 +1,116 @@
+package solar
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrBatchLength = errors.New("solar: output slices are shorter than the batch")
+	ErrBatchStep = errors.New("solar: batch step must be positive")
+)
+
+// returns the number of times start, start + step, ... that fall before end
+func GetRangeLength(start, end time.Time, step time.Duration) int {
+	if step <= 0 || !end.After(start) {
+		return 0
+	}
+	d := end.Sub(start)
+	n := int(d / step)
+	if d % step != 0 {
+		n += 1
+	}
+	return n
+}
+
+/*
+Computes the position of the sun for each of the times, storing the results
+in the corresponding elements of out, which must be at least as long as times.
+The location-dependent terms are worked out once for the whole batch. In Strict
+mode, a time past the end of the leap second table fails the whole batch with
+ErrLeapSecondTableStale before anything is computed.
+*/
+func (c *Calculator) ComputeBatch(obs Observer, times []time.Time, out []SolarPosition) error {
+	if len(out) < len(times) {
+		return ErrBatchLength
+	}
+	if err := c.validateStrict(times...); err != nil {
+		return err
+	}
+	s := newSite(obs)
+	for i, when := range times {
+		pos := &out[i]
+		*pos = SolarPosition{}
+		c.computeGeocentric(pos, when)
+		s.computeTopocentric(pos)
+	}
+	return nil
+}
+
+/*
+Computes the position of the sun at start, start + step, ... up to but not
+including end, storing the results in out, and returns the number of positions
+computed. out must hold at least GetRangeLength(start, end, step) elements.
+Strict mode applies as for ComputeBatch.
+*/
+func (c *Calculator) ComputeRange(obs Observer, start, end time.Time, step time.Duration, out []SolarPosition) (int, error) {
+	if step <= 0 {
+		return 0, ErrBatchStep
+	}
+	n := GetRangeLength(start, end, step)
+	if len(out) < n {
+		return 0, ErrBatchLength
+	}
+	if n > 0 {
+		if err := c.validateStrict(start.Add(time.Duration(n - 1) * step)); err != nil {
+			return 0, err
+		}
+	}
+	s := newSite(obs)
+	for i := 0; i < n; i++ {
+		pos := &out[i]
+		*pos = SolarPosition{}
+		c.computeGeocentric(pos, start.Add(time.Duration(i) * step))
+		s.computeTopocentric(pos)
+	}
+	return n, nil
+}
+
+/*
+Computes the refracted altitude and the azimuth of the sun in degrees for each
+of the times, storing them in altitude and azimuth, which must be at least as
+long as times. This is the batch form of Observer.Position. Strict mode applies
+as for ComputeBatch.
+*/
+func (c *Calculator) GetPositions(obs Observer, times []time.Time, altitude, azimuth []float64) error {
+	if len(altitude) < len(times) || len(azimuth) < len(times) {
+		return ErrBatchLength
+	}
+	if err := c.validateStrict(times...); err != nil {
+		return err
+	}
+	s := newSite(obs)
+	var pos SolarPosition
+	for i, when := range times {
+		c.computeGeocentric(&pos, when)
+		s.computeTopocentric(&pos)
+		altitude[i] = pos.ApparentElevation
+		azimuth[i] = pos.Azimuth
+	}
+	return nil
+}
+
+// See Calculator.ComputeBatch.
+func ComputeBatch(obs Observer, times []time.Time, out []SolarPosition) error {
+	return DefaultCalculator.ComputeBatch(obs, times, out)
+}
+
+// See Calculator.ComputeRange.
+func ComputeRange(obs Observer, start, end time.Time, step time.Duration, out []SolarPosition) (int, error) {
+	return DefaultCalculator.ComputeRange(obs, start, end, step, out)
+}
+
+// See Calculator.GetPositions.
+func GetPositions(obs Observer, times []time.Time, altitude, azimuth []float64) error {
+	return DefaultCalculator.GetPositions(obs, times, altitude, azimuth)
+}
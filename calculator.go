@@ -0,0 +1,206 @@
+package solar
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// A Warning describes a condition that degrades the accuracy of a result without preventing it.
+type Warning struct {
+	Err error // the sentinel error for the condition, such as ErrLeapSecondTableStale
+	Message string
+}
+
+func (w Warning) Error() string {
+	return w.Err.Error() + ": " + w.Message
+}
+
+func (w Warning) Unwrap() error {
+	return w.Err
+}
+
+// Diagnostics receives warnings from a Calculator.
+type Diagnostics interface {
+	Warn(w Warning)
+}
+
+// DiagnosticsFunc adapts an ordinary function to the Diagnostics interface.
+type DiagnosticsFunc func(w Warning)
+
+func (f DiagnosticsFunc) Warn(w Warning) {
+	f(w)
+}
+
+// LogDiagnostics writes warnings to the standard logger.
+var LogDiagnostics = DiagnosticsFunc(func(w Warning) {
+	log.Print(w.Error())
+})
+
+/*
+A Calculator holds the settings used to convert times and compute positions,
+and reports problems with the underlying data. Each distinct warning is passed
+to Diagnostics once; a nil Diagnostics discards them. In Strict mode the
+error-returning functions fail on conditions that would otherwise only be
+warnings.
+
+DeltaT selects the model of TT - UT used to find the UT Julian day; nil
+means the built-in table (see GetDeltaT), which is constant outside 1973-2014.
+If DUT1 is set, UT1 is instead computed directly from UTC using the published
+value of UT1 - UTC, and DeltaT is ignored.
+
+The zero value is ready to use. The package-level functions use
+DefaultCalculator, which logs warnings through the standard logger. Settings
+should not be changed while the Calculator is in use by other goroutines.
+*/
+type Calculator struct {
+	Diagnostics Diagnostics
+	Strict bool
+	DeltaT DeltaTModel
+	DUT1 DUT1Model
+
+	leapSeconds *LeapSecondTable
+	mu sync.Mutex
+	reported map[Warning]bool
+}
+
+var DefaultCalculator = &Calculator{Diagnostics: LogDiagnostics}
+
+// reports a warning to the diagnostics hook unless it has been reported before
+func (c *Calculator) warn(err error, message string) {
+	if c.Diagnostics == nil {
+		return
+	}
+	w := Warning{Err: err, Message: message}
+	c.mu.Lock()
+	if c.reported == nil {
+		c.reported = map[Warning]bool{}
+	}
+	seen := c.reported[w]
+	c.reported[w] = true
+	c.mu.Unlock()
+	if !seen {
+		c.Diagnostics.Warn(w)
+	}
+}
+
+// Forgets which warnings have been reported, so that each will be reported again.
+func (c *Calculator) ResetDiagnostics() {
+	c.mu.Lock()
+	c.reported = nil
+	c.mu.Unlock()
+}
+
+/*
+Installs a leap second table, such as one read by LoadLeapSecondTable, in
+place of the built-in one; nil restores the built-in table. A table that has
+already expired is still installed, since it is complete for earlier times,
+with a warning; times past its end are checked as they are used.
+*/
+func (c *Calculator) SetLeapSecondTable(table *LeapSecondTable) error {
+	if table == nil {
+		c.leapSeconds = nil
+		return nil
+	}
+	if err := table.Validate(); err != nil {
+		return err
+	}
+	if !time.Now().Before(table.Expires) {
+		c.warn(ErrLeapSecondTableStale, fmt.Sprintf("installed leap second table expired on %s", table.Expires.Format("2006-01-02")))
+	}
+	c.leapSeconds = table
+	return nil
+}
+
+// returns the first instant not covered by the calculator's leap second table
+func (c *Calculator) GetLeapSecondsExpiry() time.Time {
+	if c.leapSeconds == nil {
+		return GetLeapSecondsExpiry()
+	}
+	return c.leapSeconds.Expires
+}
+
+// See GetLeapSeconds.
+func (c *Calculator) GetLeapSeconds(when time.Time) int {
+	expiry := c.GetLeapSecondsExpiry()
+	if !when.Before(expiry) {
+		c.warn(ErrLeapSecondTableStale, fmt.Sprintf("leap seconds from %s on are unknown", expiry.Format("2006-01-02")))
+	}
+	if c.leapSeconds == nil {
+		return getLeapSeconds(when)
+	}
+	return c.leapSeconds.Offset(when)
+}
+
+/*
+returns TT - UT in seconds from the calculator's delta T model or, when DUT1 is
+set, from TT - UT1 = 32.184 + (TAI - UTC) - (UT1 - UTC).
+*/
+func (c *Calculator) GetDeltaT(when time.Time) float64 {
+	if c.DUT1 != nil {
+		return TtOffset + float64(c.GetLeapSeconds(when)) - c.DUT1.DUT1(when)
+	}
+	if c.DeltaT == nil {
+		return GetDeltaT(when)
+	}
+	return c.DeltaT.DeltaT(when)
+}
+
+// See GetJulianSolarDay.
+func (c *Calculator) GetJulianSolarDay(when time.Time) float64 {
+	return c.GetJulianDate(when, TimeScaleUT1).Float()
+}
+
+// See GetJulianEphemerisDay.
+func (c *Calculator) GetJulianEphemerisDay(when time.Time) float64 {
+	return c.GetJulianDate(when, TimeScaleTT).Float()
+}
+
+// See Compute.
+func (c *Calculator) Compute(obs Observer, when time.Time) SolarPosition {
+	pos := SolarPosition{}
+	c.computeGeocentric(&pos, when)
+	newSite(obs).computeTopocentric(&pos)
+	return pos
+}
+
+// See ValidateTime.
+func (c *Calculator) ValidateTime(when time.Time) error {
+	year := when.UTC().Year()
+	if year < MinValidYear || year > MaxValidYear {
+		return ErrTimeOutOfRange
+	}
+	if !when.Before(c.GetLeapSecondsExpiry()) {
+		return ErrLeapSecondTableStale
+	}
+	return nil
+}
+
+/*
+See ComputeE. A stale leap second table fails the calculation only in Strict
+mode; otherwise it is reported to Diagnostics as the position is computed.
+*/
+func (c *Calculator) ComputeE(obs Observer, when time.Time) (SolarPosition, error) {
+	if err := obs.Validate(); err != nil {
+		return SolarPosition{}, err
+	}
+	if err := c.ValidateTime(when); err != nil && (err != ErrLeapSecondTableStale || c.Strict) {
+		return SolarPosition{}, err
+	}
+	return c.Compute(obs, when), nil
+}
+
+// in Strict mode, returns ErrLeapSecondTableStale if any of the times is past the end of the leap second table
+func (c *Calculator) validateStrict(times ...time.Time) error {
+	if !c.Strict {
+		return nil
+	}
+	expiry := c.GetLeapSecondsExpiry()
+	for _, when := range times {
+		if !when.Before(expiry) {
+			return ErrLeapSecondTableStale
+		}
+	}
+	return nil
+}
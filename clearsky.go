@@ -0,0 +1,149 @@
+package solar
+
+/*
+Clear-sky models estimate the irradiance reaching the ground under a
+cloudless sky. They differ in how much they know about the atmosphere:
+Haurwitz needs only the position of the sun, Ineichen and Perez adds the Linke
+turbidity and the elevation of the site, and Bird and Hulstrom models
+aerosols, water vapour and ozone separately.
+
+See also M. Reno, C. Hansen and J. Stein, "Global Horizontal Irradiance Clear
+Sky Models: Implementation and Analysis," Sandia National Laboratories,
+SAND2012-2389, 2012.
+*/
+
+import (
+	"math"
+	"time"
+)
+
+/*
+Irradiance holds the components of the solar irradiance at a site in W/m^2:
+global and diffuse on a horizontal surface, and direct on a surface normal to
+the sun. For a consistent set, GHI = DNI * cos(zenith) + DHI.
+*/
+type Irradiance struct {
+	GHI float64 // global horizontal
+	DNI float64 // direct normal
+	DHI float64 // diffuse horizontal
+}
+
+/*
+BirdAtmosphere describes the state of the atmosphere for the Bird and Hulstrom
+clear-sky model.
+*/
+type BirdAtmosphere struct {
+	AOD380 float64 // aerosol optical depth at 380 nm
+	AOD500 float64 // aerosol optical depth at 500 nm
+	PrecipitableWater float64 // cm
+	Ozone float64 // atm-cm
+	Asymmetry float64 // aerosol forward scattering ratio
+	Albedo float64 // ground reflectance
+}
+
+// the typical atmosphere suggested by Bird and Hulstrom
+var DefaultBirdAtmosphere = BirdAtmosphere{
+	AOD380: 0.15,
+	AOD500: 0.1,
+	PrecipitableWater: 1.5,
+	Ozone: 0.3,
+	Asymmetry: 0.85,
+	Albedo: 0.2,
+}
+
+/*
+returns the clear-sky irradiance of Haurwitz (1945) for the given apparent
+zenith angle in degrees:
+
+	GHI = 1098 * cos(z) * exp(-0.059 / cos(z))
+
+The model only estimates global irradiance, so DNI and DHI are left zero; use
+a decomposition model to split it.
+*/
+func GetClearSkyHaurwitz(zenith float64) Irradiance {
+	if zenith >= 90 {
+		return Irradiance{}
+	}
+	cosZenith := math.Cos(deg2rad(zenith))
+	return Irradiance{GHI: 1098 * cosZenith * math.Exp(-0.059 / cosZenith)}
+}
+
+/*
+returns the clear-sky irradiance of Ineichen and Perez (2002) for the given
+position of the sun, site elevation in meters, air pressure in Pascal and
+Linke turbidity (typically 2 for very clean air to 6 or more for polluted or
+humid air). The beam component includes the correction from Ineichen's 2008
+"Conversion function between the Linke turbidity and the atmospheric water
+vapor and aerosol content".
+*/
+func GetClearSkyIneichen(pos SolarPosition, elevation, pressure, linkeTurbidity float64) Irradiance {
+	if pos.Zenith >= 90 {
+		return Irradiance{}
+	}
+	cosZenith := math.Cos(deg2rad(pos.Zenith))
+	airMass := GetAbsoluteAirMass(GetAirMass(pos.Zenith, AirMassKastenYoung), pressure)
+	extra := GetExtraterrestrialIrradiance(pos.RadiusVector)
+	tl := linkeTurbidity
+	fh1 := math.Exp(-elevation / 8000)
+	fh2 := math.Exp(-elevation / 1250)
+	cg1 := 5.09e-05 * elevation + 0.868
+	cg2 := 3.92e-05 * elevation + 0.0387
+
+	ghi := cg1 * extra * cosZenith * math.Max(math.Exp(-cg2 * airMass * (fh1 + fh2 * (tl - 1))), 0)
+	b := 0.664 + 0.163 / fh1
+	bnci := extra * math.Max(b * math.Exp(-0.09 * airMass * (tl - 1)), 0)
+	bnci2 := (1 - (0.1 - 0.2 * math.Exp(-tl)) / (0.1 + 0.882 / fh1)) / cosZenith
+	bnci2 = ghi * math.Min(math.Max(bnci2, 0), 1e20)
+	dni := math.Min(bnci, bnci2)
+	return Irradiance{GHI: ghi, DNI: dni, DHI: ghi - dni * cosZenith}
+}
+
+/*
+returns the clear-sky irradiance of Bird and Hulstrom (1981) for the given
+position of the sun, air pressure in Pascal and atmosphere.
+
+R. E. Bird and R. L. Hulstrom, "A Simplified Clear Sky Model for Direct and
+Diffuse Insolation on Horizontal Surfaces," SERI/TR-642-761, 1981.
+*/
+func GetClearSkyBird(pos SolarPosition, pressure float64, atm BirdAtmosphere) Irradiance {
+	if pos.Zenith >= 90 {
+		return Irradiance{}
+	}
+	cosZenith := math.Cos(deg2rad(pos.Zenith))
+	airMass := GetAirMass(pos.Zenith, AirMassKasten)
+	amPress := GetAbsoluteAirMass(airMass, pressure)
+	extra := GetExtraterrestrialIrradiance(pos.RadiusVector)
+
+	tRayleigh := math.Exp(-0.0903 * math.Pow(amPress, 0.84) * (1 + amPress - math.Pow(amPress, 1.01)))
+	amOzone := atm.Ozone * airMass
+	tOzone := 1 - 0.1611 * amOzone * math.Pow(1 + 139.48 * amOzone, -0.3034) - 0.002715 * amOzone / (1 + 0.044 * amOzone + 0.0003 * amOzone * amOzone)
+	tGases := math.Exp(-0.0127 * math.Pow(amPress, 0.26))
+	amWater := atm.PrecipitableWater * airMass
+	tWater := 1 - 2.4959 * amWater / (math.Pow(1 + 79.034 * amWater, 0.6828) + 6.385 * amWater)
+	// broadband aerosol optical depth
+	tau := 0.2758 * atm.AOD380 + 0.35 * atm.AOD500
+	tAerosol := math.Exp(-math.Pow(tau, 0.873) * (1 + tau - math.Pow(tau, 0.7088)) * math.Pow(airMass, 0.9108))
+	tAbsorb := 1 - 0.1 * (1 - airMass + math.Pow(airMass, 1.06)) * (1 - tAerosol)
+	skyAlbedo := 0.0685 + (1 - atm.Asymmetry) * (1 - tAerosol / tAbsorb)
+
+	dni := 0.9662 * extra * tAerosol * tWater * tGases * tOzone * tRayleigh
+	directHorizontal := dni * cosZenith
+	scattered := extra * cosZenith * 0.79 * tOzone * tGases * tWater * tAbsorb * (0.5 * (1 - tRayleigh) + atm.Asymmetry * (1 - tAerosol / tAbsorb)) / (1 - airMass + math.Pow(airMass, 1.02))
+	ghi := (directHorizontal + scattered) / (1 - atm.Albedo * skyAlbedo)
+	return Irradiance{GHI: ghi, DNI: dni, DHI: ghi - directHorizontal}
+}
+
+// Returns the Haurwitz clear-sky irradiance at the observer; see GetClearSkyHaurwitz.
+func (obs Observer) ClearSkyHaurwitz(when time.Time) Irradiance {
+	return GetClearSkyHaurwitz(Compute(obs, when).Zenith)
+}
+
+// Returns the Ineichen and Perez clear-sky irradiance at the observer; see GetClearSkyIneichen.
+func (obs Observer) ClearSkyIneichen(when time.Time, linkeTurbidity float64) Irradiance {
+	return GetClearSkyIneichen(Compute(obs, when), obs.Elevation, obs.pressure(), linkeTurbidity)
+}
+
+// Returns the Bird and Hulstrom clear-sky irradiance at the observer; see GetClearSkyBird.
+func (obs Observer) ClearSkyBird(when time.Time, atm BirdAtmosphere) Irradiance {
+	return GetClearSkyBird(Compute(obs, when), obs.pressure(), atm)
+}
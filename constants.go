@@ -32,6 +32,7 @@ const (
 	AirGasConstant = float64(8.31432) // N*m/s^2
 	EarthGravity = float64(9.80665) // m/s^2
 	EarthAtmosphereMolarMass = 0.0289644 // kg/mol
+	StandardRefraction = float64(0.5667) // degrees, refraction at the horizon used for rising and setting
 )
 
 var aberationCoeffs map[string]func(float64) float64
@@ -416,3 +417,141 @@ var SunEarthDistanceCoeffs = [][][3]float64{
 		[3]float64{4,2.56,6283.08},
 	},
 }
+
+/*
+Periodic terms for the longitude and distance of the moon, from table 47.A of
+J. Meeus, Astronomical Algorithms, 2nd ed., 1998, a truncation of the
+ELP-2000/82 theory. Each row holds the multiples of D, M, M' and F, then the
+coefficients of the sine for longitude (1e-6 degrees) and of the cosine for
+distance (1e-3 km).
+*/
+var MoonLongitudeDistanceTerms = [][6]float64{
+	[6]float64{0,0,1,0,6288774,-20905355},
+	[6]float64{2,0,-1,0,1274027,-3699111},
+	[6]float64{2,0,0,0,658314,-2955968},
+	[6]float64{0,0,2,0,213618,-569925},
+	[6]float64{0,1,0,0,-185116,48888},
+	[6]float64{0,0,0,2,-114332,-3149},
+	[6]float64{2,0,-2,0,58793,246158},
+	[6]float64{2,-1,-1,0,57066,-152138},
+	[6]float64{2,0,1,0,53322,-170733},
+	[6]float64{2,-1,0,0,45758,-204586},
+	[6]float64{0,1,-1,0,-40923,-129620},
+	[6]float64{1,0,0,0,-34720,108743},
+	[6]float64{0,1,1,0,-30383,104755},
+	[6]float64{2,0,0,-2,15327,10321},
+	[6]float64{0,0,1,2,-12528,0},
+	[6]float64{0,0,1,-2,10980,79661},
+	[6]float64{4,0,-1,0,10675,-34782},
+	[6]float64{0,0,3,0,10034,-23210},
+	[6]float64{4,0,-2,0,8548,-21636},
+	[6]float64{2,1,-1,0,-7888,24208},
+	[6]float64{2,1,0,0,-6766,30824},
+	[6]float64{1,0,-1,0,-5163,-8379},
+	[6]float64{1,1,0,0,4987,-16675},
+	[6]float64{2,-1,1,0,4036,-12831},
+	[6]float64{2,0,2,0,3994,-10445},
+	[6]float64{4,0,0,0,3861,-11650},
+	[6]float64{2,0,-3,0,3665,14403},
+	[6]float64{0,1,-2,0,-2689,-7003},
+	[6]float64{2,0,-1,2,-2602,0},
+	[6]float64{2,-1,-2,0,2390,10056},
+	[6]float64{1,0,1,0,-2348,6322},
+	[6]float64{2,-2,0,0,2236,-9884},
+	[6]float64{0,1,2,0,-2120,5751},
+	[6]float64{0,2,0,0,-2069,0},
+	[6]float64{2,-2,-1,0,2048,-4950},
+	[6]float64{2,0,1,-2,-1773,4130},
+	[6]float64{2,0,0,2,-1595,0},
+	[6]float64{4,-1,-1,0,1215,-3958},
+	[6]float64{0,0,2,2,-1110,0},
+	[6]float64{3,0,-1,0,-892,3258},
+	[6]float64{2,1,1,0,-810,2616},
+	[6]float64{4,-1,-2,0,759,-1897},
+	[6]float64{0,2,-1,0,-713,-2117},
+	[6]float64{2,2,-1,0,-700,2354},
+	[6]float64{2,1,-2,0,691,0},
+	[6]float64{2,-1,0,-2,596,0},
+	[6]float64{4,0,1,0,549,-1423},
+	[6]float64{0,0,4,0,537,-1117},
+	[6]float64{4,-1,0,0,520,-1571},
+	[6]float64{1,0,-2,0,-487,-1739},
+	[6]float64{2,1,0,-2,-399,0},
+	[6]float64{0,0,2,-2,-381,-4421},
+	[6]float64{1,1,1,0,351,0},
+	[6]float64{3,0,-2,0,-340,0},
+	[6]float64{4,0,-3,0,330,0},
+	[6]float64{2,-1,2,0,327,0},
+	[6]float64{0,2,1,0,-323,1165},
+	[6]float64{1,1,-1,0,299,0},
+	[6]float64{2,0,3,0,294,0},
+	[6]float64{2,0,-1,-2,0,8752},
+}
+
+/*
+Periodic terms for the latitude of the moon, from table 47.B of Meeus. Each row
+holds the multiples of D, M, M' and F, then the coefficient of the sine
+(1e-6 degrees).
+*/
+var MoonLatitudeTerms = [][5]float64{
+	[5]float64{0,0,0,1,5128122},
+	[5]float64{0,0,1,1,280602},
+	[5]float64{0,0,1,-1,277693},
+	[5]float64{2,0,0,-1,173237},
+	[5]float64{2,0,-1,1,55413},
+	[5]float64{2,0,-1,-1,46271},
+	[5]float64{2,0,0,1,32573},
+	[5]float64{0,0,2,1,17198},
+	[5]float64{2,0,1,-1,9266},
+	[5]float64{0,0,2,-1,8822},
+	[5]float64{2,-1,0,-1,8216},
+	[5]float64{2,0,-2,-1,4324},
+	[5]float64{2,0,1,1,4200},
+	[5]float64{2,1,0,-1,-3359},
+	[5]float64{2,-1,-1,1,2463},
+	[5]float64{2,-1,0,1,2211},
+	[5]float64{2,-1,-1,-1,2065},
+	[5]float64{0,1,-1,-1,-1870},
+	[5]float64{4,0,-1,-1,1828},
+	[5]float64{0,1,0,1,-1794},
+	[5]float64{0,0,0,3,-1749},
+	[5]float64{0,1,-1,1,-1565},
+	[5]float64{1,0,0,1,-1491},
+	[5]float64{0,1,1,1,-1475},
+	[5]float64{0,1,1,-1,-1410},
+	[5]float64{0,1,0,-1,-1344},
+	[5]float64{1,0,0,-1,-1335},
+	[5]float64{0,0,3,1,1107},
+	[5]float64{4,0,0,-1,1021},
+	[5]float64{4,0,-1,1,833},
+	[5]float64{0,0,1,-3,777},
+	[5]float64{4,0,-2,1,671},
+	[5]float64{2,0,0,-3,607},
+	[5]float64{2,0,2,-1,596},
+	[5]float64{2,-1,1,-1,491},
+	[5]float64{2,0,-2,1,-451},
+	[5]float64{0,0,3,-1,439},
+	[5]float64{2,0,2,1,422},
+	[5]float64{2,0,-3,-1,421},
+	[5]float64{2,1,-1,1,-366},
+	[5]float64{2,1,0,1,-351},
+	[5]float64{4,0,0,1,331},
+	[5]float64{2,-1,1,1,315},
+	[5]float64{2,-2,0,-1,302},
+	[5]float64{0,0,1,3,-283},
+	[5]float64{2,1,1,-1,-229},
+	[5]float64{1,1,0,-1,223},
+	[5]float64{1,1,0,1,223},
+	[5]float64{0,1,-2,-1,-220},
+	[5]float64{2,1,-1,-1,-220},
+	[5]float64{1,0,1,1,-185},
+	[5]float64{2,-1,-2,-1,181},
+	[5]float64{0,1,2,1,-177},
+	[5]float64{4,0,-2,-1,176},
+	[5]float64{4,-1,-1,-1,166},
+	[5]float64{1,0,1,-1,-164},
+	[5]float64{4,0,1,-1,132},
+	[5]float64{1,0,-1,-1,-119},
+	[5]float64{4,-1,0,-1,115},
+	[5]float64{2,-2,0,1,107},
+}
@@ -0,0 +1,362 @@
+package solar
+
+/*
+Decomposition models split measured global horizontal irradiance into its
+direct and diffuse parts, using empirical fits of the diffuse fraction or the
+beam transmittance against the clearness index, the ratio of the irradiance
+at the ground to that at the top of the atmosphere.
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	MinCosZenith = float64(0.065) // smallest cosine of the zenith used for the clearness index, about 86.3 degrees
+	MaxDecompositionZenith = float64(87) // degrees, beyond which decomposition models give no beam irradiance
+	MaxDISCAirMass = float64(12)
+)
+
+var (
+	ErrDirintFormat = errors.New("solar: DIRINT coefficient file must hold 6 x 6 x 7 x 5 numbers")
+	ErrDirintCoefficients = errors.New("solar: DIRINT needs a coefficient table")
+	ErrSeriesLength = errors.New("solar: input series must have the same length")
+)
+
+/*
+Decomposition is the result of a decomposition model: the global irradiance
+it was given, the direct and diffuse irradiance it derived, and the clearness
+index and extraterrestrial normal irradiance that went into it.
+*/
+type Decomposition struct {
+	Irradiance
+	ClearnessIndex float64
+	Extraterrestrial float64 // W/m^2 normal to the sun at the top of the atmosphere
+}
+
+/*
+returns the clearness index kt, the ratio of the global horizontal irradiance
+to the extraterrestrial irradiance on a horizontal surface, limited to [0, 1].
+The cosine of the zenith is held at MinCosZenith or above so that the index
+stays finite near the horizon.
+*/
+func GetClearnessIndex(ghi, zenith, extraterrestrial float64) float64 {
+	cosZenith := math.Max(math.Cos(deg2rad(zenith)), MinCosZenith)
+	return math.Min(math.Max(ghi / (extraterrestrial * cosZenith), 0), 1)
+}
+
+/*
+returns the zenith independent clearness index kt' of Perez et al. (1990),
+which removes the dependence of kt on the air mass.
+*/
+func GetZenithIndependentClearnessIndex(clearnessIndex, airMass float64) float64 {
+	kt := clearnessIndex / (1.031 * math.Exp(-1.4 / (0.9 + 9.4 / airMass)) + 0.1)
+	return math.Min(math.Max(kt, 0), 1)
+}
+
+func newDecomposition(ghi float64, pos SolarPosition) Decomposition {
+	d := Decomposition{Extraterrestrial: GetExtraterrestrialIrradiance(pos.RadiusVector)}
+	d.GHI = ghi
+	d.ClearnessIndex = GetClearnessIndex(ghi, pos.Zenith, d.Extraterrestrial)
+	return d
+}
+
+// fills in DNI and DHI from a diffuse fraction, treating the sun near the horizon as giving no beam
+func (d *Decomposition) setDiffuseFraction(fraction, zenith float64) {
+	d.DHI = fraction * d.GHI
+	d.DNI = (d.GHI - d.DHI) / math.Cos(deg2rad(zenith))
+	if zenith > MaxDecompositionZenith || d.GHI < 0 || d.DNI < 0 {
+		d.DNI = 0
+		d.DHI = d.GHI
+	}
+}
+
+// fills in DNI and DHI from a beam irradiance, treating the sun near the horizon as giving no beam
+func (d *Decomposition) setDNI(dni, zenith float64) {
+	if zenith > MaxDecompositionZenith || d.GHI < 0 || !(dni >= 0) {
+		dni = 0
+	}
+	d.DNI = dni
+	d.DHI = d.GHI - dni * math.Cos(deg2rad(zenith))
+}
+
+/*
+splits the global horizontal irradiance in W/m^2 using the diffuse fraction
+correlation of Erbs, Klein and Duffie (1982).
+*/
+func GetDecompositionErbs(ghi float64, pos SolarPosition) Decomposition {
+	d := newDecomposition(ghi, pos)
+	kt := d.ClearnessIndex
+	var fraction float64
+	switch {
+	case kt <= 0.22:
+		fraction = 1 - 0.09 * kt
+	case kt <= 0.8:
+		fraction = polynomial(kt, 0.9511, -0.1604, 4.388, -16.638, 12.336)
+	default:
+		fraction = 0.165
+	}
+	d.setDiffuseFraction(fraction, pos.Zenith)
+	return d
+}
+
+/*
+splits the global horizontal irradiance in W/m^2 using the logistic diffuse
+fraction of Boland, Scharbius and Huang (2008), with the coefficients fitted
+to 15 minute data by Lauret et al.
+*/
+func GetDecompositionBoland(ghi float64, pos SolarPosition) Decomposition {
+	d := newDecomposition(ghi, pos)
+	d.setDiffuseFraction(1 / (1 + math.Exp(8.645 * (d.ClearnessIndex - 0.613))), pos.Zenith)
+	return d
+}
+
+// returns the DISC direct beam transmittance Kn for the given clearness index and air mass
+func getDISCTransmittance(kt, airMass float64) float64 {
+	am := math.Min(airMass, MaxDISCAirMass)
+	var a, b, c float64
+	if kt <= 0.6 {
+		a = polynomial(kt, 0.512, -1.56, 2.286, -2.222)
+		b = polynomial(kt, 0.37, 0.962)
+		c = polynomial(kt, -0.28, 0.932, -2.048)
+	} else {
+		a = polynomial(kt, -5.743, 21.77, -27.49, 11.56)
+		b = polynomial(kt, 41.4, -118.5, 66.05, 31.9)
+		c = polynomial(kt, -47.01, 184.2, -222.0, 73.81)
+	}
+	knc := polynomial(am, 0.866, -0.122, 0.0121, -0.000653, 0.000014)
+	return knc - (a + b * math.Exp(c * am))
+}
+
+// returns the pressure corrected Kasten (1966) air mass used by DISC and DIRINT
+func getDISCAirMass(zenith, pressure float64) float64 {
+	return GetAbsoluteAirMass(GetAirMass(zenith, AirMassKasten), pressure)
+}
+
+/*
+derives the direct normal irradiance from the global horizontal irradiance in
+W/m^2 with the DISC model of Maxwell (1987), for the given air pressure in
+Pascal.
+
+E. L. Maxwell, "A Quasi-Physical Model for Converting Hourly Global Horizontal
+to Direct Normal Insolation," SERI/TR-215-3087, 1987.
+*/
+func GetDecompositionDISC(ghi float64, pos SolarPosition, pressure float64) Decomposition {
+	d := newDecomposition(ghi, pos)
+	if pos.Zenith > MaxDecompositionZenith {
+		d.setDNI(0, pos.Zenith)
+		return d
+	}
+	kn := getDISCTransmittance(d.ClearnessIndex, getDISCAirMass(pos.Zenith, pressure))
+	d.setDNI(kn * d.Extraterrestrial, pos.Zenith)
+	return d
+}
+
+/*
+DirintCoefficients is the table of correction factors for the DIRINT model,
+indexed by bins of the zenith independent clearness index kt' (6), the zenith
+angle (6), the stability index delta kt' (7, the last for "unknown") and the
+precipitable water (5, the last for "unknown").
+
+The table published by Perez et al. is not distributed with this package; load
+it with LoadDirintCoefficients.
+*/
+type DirintCoefficients [6][6][7][5]float64
+
+/*
+Parses a DIRINT coefficient table: 1260 numbers separated by white space or
+commas, in the order of the indexes of DirintCoefficients with the last index
+varying fastest, as in the original Fortran and in pvlib.
+*/
+func ParseDirintCoefficients(r io.Reader) (*DirintCoefficients, error) {
+	coeffs := &DirintCoefficients{}
+	values := []float64{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		words := strings.FieldsFunc(scanner.Text(), func(c rune) bool {
+			return c == ',' || unicode.IsSpace(c)
+		})
+		for _, word := range words {
+			v, err := strconv.ParseFloat(word, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: bad value %q", ErrDirintFormat, word)
+			}
+			values = append(values, v)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(values) != 6 * 6 * 7 * 5 {
+		return nil, fmt.Errorf("%w: found %d", ErrDirintFormat, len(values))
+	}
+	n := 0
+	for i := range coeffs {
+		for j := range coeffs[i] {
+			for k := range coeffs[i][j] {
+				for l := range coeffs[i][j][k] {
+					coeffs[i][j][k][l] = values[n]
+					n += 1
+				}
+			}
+		}
+	}
+	return coeffs, nil
+}
+
+// Reads a DIRINT coefficient table from a file; see ParseDirintCoefficients.
+func LoadDirintCoefficients(fn string) (*DirintCoefficients, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	return ParseDirintCoefficients(bytes.NewReader(data))
+}
+
+// returns the index of the first bound that x is below, or len(bounds) if it is above them all
+func getBin(x float64, bounds ...float64) int {
+	for i, bound := range bounds {
+		if x < bound {
+			return i
+		}
+	}
+	return len(bounds)
+}
+
+/*
+derives the direct normal irradiance from a series of global horizontal
+irradiance samples in W/m^2 with the DIRINT model of Perez et al. (1992),
+which corrects DISC according to the stability of the sky, taken from the
+neighbouring samples, and the precipitable water, taken from the dew point
+in Kelvin. The samples should be evenly spaced, no more than an hour apart.
+dewPoint may be nil if it is not known, and a single sample is treated as
+having unknown stability.
+
+R. Perez, P. Ineichen, E. Maxwell, R. Seals and A. Zelenka, "Dynamic Global-
+to-Direct Irradiance Conversion Models," ASHRAE Transactions 98(1), 1992.
+*/
+func GetDecompositionDIRINT(ghi []float64, positions []SolarPosition, pressure float64, dewPoint []float64, coeffs *DirintCoefficients) ([]Decomposition, error) {
+	if coeffs == nil {
+		return nil, ErrDirintCoefficients
+	}
+	n := len(ghi)
+	if len(positions) != n || (dewPoint != nil && len(dewPoint) != n) {
+		return nil, ErrSeriesLength
+	}
+	result := make([]Decomposition, n)
+	ktPrime := make([]float64, n)
+	for i := range ghi {
+		result[i] = GetDecompositionDISC(ghi[i], positions[i], pressure)
+		ktPrime[i] = GetZenithIndependentClearnessIndex(result[i].ClearnessIndex, getDISCAirMass(positions[i].Zenith, pressure))
+	}
+	for i := range result {
+		zenith := positions[i].Zenith
+		if zenith > MaxDecompositionZenith {
+			continue
+		}
+		stability := 6
+		if n > 1 {
+			var delta float64
+			switch i {
+			case 0:
+				delta = math.Abs(ktPrime[0] - ktPrime[1])
+			case n - 1:
+				delta = math.Abs(ktPrime[n - 1] - ktPrime[n - 2])
+			default:
+				delta = 0.5 * (math.Abs(ktPrime[i] - ktPrime[i + 1]) + math.Abs(ktPrime[i] - ktPrime[i - 1]))
+			}
+			stability = getBin(delta, 0.015, 0.035, 0.07, 0.15, 0.3)
+		}
+		water := 4
+		if dewPoint != nil {
+			w := math.Exp(0.07 * (dewPoint[i] - CelsiusOffset) - 0.075)
+			water = getBin(w, 1, 2, 3)
+		}
+		c := coeffs[getBin(ktPrime[i], 0.24, 0.4, 0.56, 0.7, 0.8)][getBin(zenith, 25, 40, 55, 70, 80)][stability][water]
+		result[i].setDNI(result[i].DNI * c, zenith)
+	}
+	return result, nil
+}
+
+/*
+splits a series of global horizontal irradiance samples in W/m^2 with the
+multiple predictor logistic model of Ridley, Boland and Lauret (2010), which
+adds the apparent solar time, the solar altitude, the daily clearness index and
+the persistence of the clearness index between neighbouring samples to the
+simple Boland model. The samples should be hourly; the daily clearness index is
+taken over the samples on the same calendar day.
+
+B. Ridley, J. Boland and P. Lauret, "Modelling of diffuse solar fraction with
+multiple predictors," Renewable Energy 35(2), 2010.
+*/
+func GetDecompositionBRL(ghi []float64, positions []SolarPosition) ([]Decomposition, error) {
+	n := len(ghi)
+	if len(positions) != n {
+		return nil, ErrSeriesLength
+	}
+	result := make([]Decomposition, n)
+	for i := range ghi {
+		result[i] = newDecomposition(ghi[i], positions[i])
+	}
+	for start := 0; start < n; {
+		year, month, day := positions[start].Time.Date()
+		end := start
+		sumGHI := 0.0
+		sumExtra := 0.0
+		for ; end < n; end++ {
+			y, m, d := positions[end].Time.Date()
+			if y != year || m != month || d != day {
+				break
+			}
+			if positions[end].Zenith < 90 {
+				sumGHI += ghi[end]
+				sumExtra += result[end].Extraterrestrial * math.Cos(deg2rad(positions[end].Zenith))
+			}
+		}
+		daily := 0.0
+		if sumExtra > 0 {
+			daily = math.Min(sumGHI / sumExtra, 1)
+		}
+		for i := start; i < end; i++ {
+			pos := positions[i]
+			kt := result[i].ClearnessIndex
+			daylight := func(j int) bool {
+				return j >= start && j < end && positions[j].Zenith < 90
+			}
+			persistence := kt
+			switch {
+			case daylight(i - 1) && daylight(i + 1):
+				persistence = (result[i - 1].ClearnessIndex + result[i + 1].ClearnessIndex) / 2
+			case daylight(i - 1):
+				persistence = result[i - 1].ClearnessIndex
+			case daylight(i + 1):
+				persistence = result[i + 1].ClearnessIndex
+			}
+			solarTime := 12 + limitDegrees180(pos.LocalHourAngle) / 15
+			x := -5.38 + 6.63 * kt + 0.006 * solarTime - 0.007 * (90 - pos.Zenith) + 1.75 * daily + 1.31 * persistence
+			result[i].setDiffuseFraction(1 / (1 + math.Exp(x)), pos.Zenith)
+		}
+		start = end
+	}
+	return result, nil
+}
+
+// Returns the Erbs decomposition of a GHI measurement at the observer; see GetDecompositionErbs.
+func (obs Observer) DecompositionErbs(ghi float64, when time.Time) Decomposition {
+	return GetDecompositionErbs(ghi, Compute(obs, when))
+}
+
+// Returns the DISC decomposition of a GHI measurement at the observer; see GetDecompositionDISC.
+func (obs Observer) DecompositionDISC(ghi float64, when time.Time) Decomposition {
+	return GetDecompositionDISC(ghi, Compute(obs, when), obs.pressure())
+}
@@ -0,0 +1,294 @@
+package solar
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrDeltaTFormat = errors.New("solar: malformed delta T file")
+	ErrDeltaTTableEmpty = errors.New("solar: delta T table has no entries")
+)
+
+// A DeltaTModel gives the difference TT - UT in seconds at a given time.
+type DeltaTModel interface {
+	DeltaT(when time.Time) float64
+}
+
+// DeltaTFunc adapts an ordinary function to the DeltaTModel interface.
+type DeltaTFunc func(when time.Time) float64
+
+func (f DeltaTFunc) DeltaT(when time.Time) float64 {
+	return f(when)
+}
+
+var (
+	// the monthly table built into the package, clamped outside 1973-2014; see GetDeltaT
+	BuiltinDeltaT = DeltaTFunc(GetDeltaT)
+	// see GetDeltaTEspenakMeeus
+	EspenakMeeusDeltaT = DeltaTFunc(GetDeltaTEspenakMeeus)
+	// see GetDeltaTMorrisonStephenson
+	MorrisonStephensonDeltaT = DeltaTFunc(GetDeltaTMorrisonStephenson)
+)
+
+// returns the year including the fraction elapsed, e.g. 2000.5 at the start of July 2, 2000
+func GetDecimalYear(when time.Time) float64 {
+	when = when.UTC()
+	year := when.Year()
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(year + 1, time.January, 1, 0, 0, 0, 0, time.UTC)
+	return float64(year) + float64(when.Sub(start)) / float64(end.Sub(start))
+}
+
+/*
+returns delta T in seconds from the long-term parabola of Morrison and
+Stephenson (2004), fitted to historical eclipse records:
+
+	delta T = -20 + 32 * ((year - 1820) / 100) ^ 2
+
+It is only a rough guide within a few centuries of the present.
+*/
+func GetDeltaTMorrisonStephenson(when time.Time) float64 {
+	u := (GetDecimalYear(when) - 1820) / 100
+	return -20 + 32 * u * u
+}
+
+// evaluates a polynomial with coefficients in increasing order of power
+func polynomial(x float64, coeffs ...float64) float64 {
+	result := 0.0
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		result = result * x + coeffs[i]
+	}
+	return result
+}
+
+/*
+returns delta T in seconds from the piecewise polynomials of Espenak and
+Meeus (2006), as used for the NASA Five Millennium Canon of Solar Eclipses.
+They cover the years -500 to 2150 and fall back on the Morrison and Stephenson
+parabola outside that range.
+
+https://eclipse.gsfc.nasa.gov/SEcat5/deltatpoly.html
+*/
+func GetDeltaTEspenakMeeus(when time.Time) float64 {
+	y := GetDecimalYear(when)
+	switch {
+	case y < -500:
+		return GetDeltaTMorrisonStephenson(when)
+	case y < 500:
+		return polynomial(y / 100, 10583.6, -1014.41, 33.78311, -5.952053, -0.1798452, 0.022174192, 0.0090316521)
+	case y < 1600:
+		return polynomial((y - 1000) / 100, 1574.2, -556.01, 71.23472, 0.319781, -0.8503463, -0.005050998, 0.0083572073)
+	case y < 1700:
+		return polynomial(y - 1600, 120, -0.9808, -0.01532, 1.0 / 7129)
+	case y < 1800:
+		return polynomial(y - 1700, 8.83, 0.1603, -0.0059285, 0.00013336, -1.0 / 1174000)
+	case y < 1860:
+		return polynomial(y - 1800, 13.72, -0.332447, 0.0068612, 0.0041116, -0.00037436, 0.0000121272, -0.0000001699, 0.000000000875)
+	case y < 1900:
+		return polynomial(y - 1860, 7.62, 0.5737, -0.251754, 0.01680668, -0.0004473624, 1.0 / 233174)
+	case y < 1920:
+		return polynomial(y - 1900, -2.79, 1.494119, -0.0598939, 0.0061966, -0.000197)
+	case y < 1941:
+		return polynomial(y - 1920, 21.20, 0.84493, -0.076100, 0.0020936)
+	case y < 1961:
+		return polynomial(y - 1950, 29.07, 0.407, -1.0 / 233, 1.0 / 2547)
+	case y < 1986:
+		return polynomial(y - 1975, 45.45, 1.067, -1.0 / 260, -1.0 / 718)
+	case y < 2005:
+		return polynomial(y - 2000, 63.86, 0.3345, -0.060374, 0.0017275, 0.000651814, 0.00002373599)
+	case y < 2050:
+		return polynomial(y - 2000, 62.92, 0.32217, 0.005589)
+	case y < 2150:
+		return GetDeltaTMorrisonStephenson(when) - 0.5628 * (2150 - y)
+	}
+	return GetDeltaTMorrisonStephenson(when)
+}
+
+/*
+DeltaTTable holds measured or predicted values of delta T, such as those
+published by the IERS and USNO, and interpolates linearly between them.
+Outside the table the Fallback model is used, or GetDeltaTEspenakMeeus if
+Fallback is nil.
+*/
+type DeltaTTable struct {
+	Times []time.Time
+	Values []float64 // seconds
+	Fallback DeltaTModel
+}
+
+func (table *DeltaTTable) DeltaT(when time.Time) float64 {
+	n := len(table.Times)
+	if n == 0 || when.Before(table.Times[0]) || when.After(table.Times[n - 1]) {
+		if table.Fallback == nil {
+			return GetDeltaTEspenakMeeus(when)
+		}
+		return table.Fallback.DeltaT(when)
+	}
+	i := sort.Search(n, func(i int) bool {
+		return table.Times[i].After(when)
+	})
+	if i == n {
+		return table.Values[n - 1]
+	}
+	t0 := table.Times[i - 1]
+	f := float64(when.Sub(t0)) / float64(table.Times[i].Sub(t0))
+	return table.Values[i - 1] + f * (table.Values[i] - table.Values[i - 1])
+}
+
+func (table *DeltaTTable) add(when time.Time, value float64) error {
+	n := len(table.Times)
+	if n > 0 && !when.After(table.Times[n - 1]) {
+		return fmt.Errorf("%w: entries out of order at %s", ErrDeltaTFormat, when.Format("2006-01-02"))
+	}
+	table.Times = append(table.Times, when)
+	table.Values = append(table.Values, value)
+	return nil
+}
+
+/*
+Parses a deltat.data file as published by the USNO and IERS, with lines of
+year, month, day and delta T in seconds:
+
+	1973  2  1  43.4724
+*/
+func ParseDeltaTData(r io.Reader) (*DeltaTTable, error) {
+	table := &DeltaTTable{}
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	for scanner.Scan() {
+		lineno += 1
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("%w: line %d", ErrDeltaTFormat, lineno)
+		}
+		var ymd [3]int
+		for i := range ymd {
+			v, err := strconv.Atoi(fields[i])
+			if err != nil {
+				return nil, fmt.Errorf("%w: bad date at line %d", ErrDeltaTFormat, lineno)
+			}
+			ymd[i] = v
+		}
+		value, err := strconv.ParseFloat(fields[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad value at line %d", ErrDeltaTFormat, lineno)
+		}
+		if err := table.add(time.Date(ymd[0], time.Month(ymd[1]), ymd[2], 0, 0, 0, 0, time.UTC), value); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(table.Times) == 0 {
+		return nil, ErrDeltaTTableEmpty
+	}
+	return table, nil
+}
+
+/*
+parses the daily UT1-UTC values from an IERS finals file (finals.all,
+finals2000A.data and the like), which has fixed columns: the modified Julian
+date in columns 8-15 and UT1-UTC in seconds in columns 59-68. Lines without a
+UT1-UTC value, at the end of the prediction span, are skipped.
+*/
+func parseFinals(r io.Reader) ([]time.Time, []float64, error) {
+	times := []time.Time{}
+	values := []float64{}
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	for scanner.Scan() {
+		lineno += 1
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if len(line) < 68 {
+			if len(line) >= 15 {
+				continue
+			}
+			return nil, nil, fmt.Errorf("%w: short line %d", ErrDeltaTFormat, lineno)
+		}
+		mjd, err := strconv.ParseFloat(strings.TrimSpace(line[7:15]), 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: bad MJD at line %d", ErrDeltaTFormat, lineno)
+		}
+		field := strings.TrimSpace(line[58:68])
+		if field == "" {
+			continue
+		}
+		dut1, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: bad UT1-UTC at line %d", ErrDeltaTFormat, lineno)
+		}
+		times = append(times, time.Unix(int64(math.Round((mjd - MjdUnixEpoch) * 86400)), 0).UTC())
+		values = append(values, dut1)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+	return times, values, nil
+}
+
+/*
+Builds a delta T table from an IERS finals file of daily UT1-UTC values, using
+
+	delta T = 32.184 + (TAI - UTC) - (UT1 - UTC)
+
+with TAI - UTC taken from the given leap second table, or the built-in one if
+it is nil.
+*/
+func ParseFinalsDeltaT(r io.Reader, leapSeconds *LeapSecondTable) (*DeltaTTable, error) {
+	if leapSeconds == nil {
+		leapSeconds = GetBuiltinLeapSecondTable()
+	}
+	times, values, err := parseFinals(r)
+	if err != nil {
+		return nil, err
+	}
+	table := &DeltaTTable{}
+	for i, t := range times {
+		if err := table.add(t, TtOffset + float64(leapSeconds.Offset(t)) - values[i]); err != nil {
+			return nil, err
+		}
+	}
+	if len(table.Times) == 0 {
+		return nil, ErrDeltaTTableEmpty
+	}
+	return table, nil
+}
+
+/*
+Reads a delta T table from a file in either the deltat.data or the IERS
+finals format, telling them apart by the length of the first data line.
+*/
+func LoadDeltaTTable(fn string, leapSeconds *LeapSecondTable) (*DeltaTTable, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if len(strings.TrimRight(line, "\r")) >= 68 {
+			return ParseFinalsDeltaT(bytes.NewReader(data), leapSeconds)
+		}
+		break
+	}
+	return ParseDeltaTData(bytes.NewReader(data))
+}
@@ -0,0 +1,85 @@
+package solar
+
+import (
+	"errors"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+var (
+	ErrDUT1SeriesEmpty = errors.New("solar: UT1-UTC series has no entries")
+	ErrDUT1SeriesOrder = errors.New("solar: UT1-UTC series entries are out of order")
+)
+
+// A DUT1Model gives the difference UT1 - UTC in seconds at a given time, as published in IERS Bulletin A.
+type DUT1Model interface {
+	DUT1(when time.Time) float64
+}
+
+// ConstantDUT1 is a single value of UT1 - UTC in seconds, such as the DUT1 broadcast with time signals.
+type ConstantDUT1 float64
+
+func (dut1 ConstantDUT1) DUT1(when time.Time) float64 {
+	return float64(dut1)
+}
+
+/*
+DUT1Series holds daily values of UT1 - UTC and interpolates linearly between
+them, treating the one second jumps at leap seconds as steps. Times outside
+the series get the nearest value.
+*/
+type DUT1Series struct {
+	Times []time.Time
+	Values []float64 // seconds
+}
+
+func (series *DUT1Series) DUT1(when time.Time) float64 {
+	n := len(series.Times)
+	if n == 0 {
+		return 0
+	}
+	i := sort.Search(n, func(i int) bool {
+		return series.Times[i].After(when)
+	})
+	if i == 0 {
+		return series.Values[0]
+	}
+	if i == n {
+		return series.Values[n - 1]
+	}
+	t0 := series.Times[i - 1]
+	f := float64(when.Sub(t0)) / float64(series.Times[i].Sub(t0))
+	diff := series.Values[i] - series.Values[i - 1]
+	diff -= math.Round(diff)
+	return series.Values[i - 1] + f * diff
+}
+
+// Parses the UT1 - UTC values from an IERS Bulletin A finals file (finals.data, finals2000A.all and the like).
+func ParseFinalsDUT1(r io.Reader) (*DUT1Series, error) {
+	times, values, err := parseFinals(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(times) == 0 {
+		return nil, ErrDUT1SeriesEmpty
+	}
+	for i := 1; i < len(times); i++ {
+		if !times[i].After(times[i - 1]) {
+			return nil, ErrDUT1SeriesOrder
+		}
+	}
+	return &DUT1Series{Times: times, Values: values}, nil
+}
+
+// Reads a UT1 - UTC series from an IERS Bulletin A finals file.
+func LoadDUT1Series(fn string) (*DUT1Series, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseFinalsDUT1(f)
+}
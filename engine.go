@@ -0,0 +1,225 @@
+package solar
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultBatchChunkSize = 4096 // positions computed and delivered together by a BatchEngine
+	batchCancelCheck = 256 // positions computed between checks for cancellation
+)
+
+/*
+BatchJob is a series of times at which to compute the position of the sun for
+one observer: either the Times given, or if Times is nil, Start, Start + Step,
+... up to but not including End.
+*/
+type BatchJob struct {
+	Observer Observer
+	Times []time.Time
+	Start time.Time
+	End time.Time
+	Step time.Duration
+}
+
+func (job BatchJob) length() int {
+	if job.Times != nil {
+		return len(job.Times)
+	}
+	return GetRangeLength(job.Start, job.End, job.Step)
+}
+
+func (job BatchJob) time(i int) time.Time {
+	if job.Times != nil {
+		return job.Times[i]
+	}
+	return job.Start.Add(time.Duration(i) * job.Step)
+}
+
+/*
+BatchResult is a chunk of the positions of a BatchJob: Positions[i] is the
+position at the job's time number Offset + i. Done is set on the last chunk of
+each job, which for a job with no times is the only, empty, chunk. Err is only
+set by Stream, on a final result without positions and with Job -1, when the
+run fails.
+*/
+type BatchResult struct {
+	Job int // index of the job in the slice given to the engine
+	Offset int
+	Positions []SolarPosition
+	Done bool
+	Err error
+}
+
+/*
+BatchEngine computes the positions for many jobs on a pool of goroutines. The
+work is split into chunks of at most ChunkSize positions, and the results are
+delivered in order, job by job and chunk by chunk, exactly as a single
+goroutine would produce them. At most a few chunks per worker are held in
+memory at once, so very long jobs can be streamed.
+*/
+type BatchEngine struct {
+	Calculator *Calculator // nil for DefaultCalculator
+	Workers int // 0 for runtime.GOMAXPROCS(0)
+	ChunkSize int // 0 for DefaultBatchChunkSize
+}
+
+type batchTask struct {
+	job *BatchJob
+	result BatchResult
+	done chan struct{}
+}
+
+func (e *BatchEngine) calculator() *Calculator {
+	if e.Calculator == nil {
+		return DefaultCalculator
+	}
+	return e.Calculator
+}
+
+func (e *BatchEngine) compute(ctx context.Context, task *batchTask) {
+	defer close(task.done)
+	calc := e.calculator()
+	s := newSite(task.job.Observer)
+	for i := range task.result.Positions {
+		if i % batchCancelCheck == 0 {
+			if ctx.Err() != nil {
+				return
+			}
+		}
+		pos := &task.result.Positions[i]
+		calc.computeGeocentric(pos, task.job.time(task.result.Offset + i))
+		s.computeTopocentric(pos)
+	}
+}
+
+/*
+Computes the positions for the jobs and passes each chunk of results to fn, in
+order and from the calling goroutine. It stops early when ctx is cancelled or
+its deadline passes, returning ctx.Err(), or when fn returns an error, which
+is returned. The Positions slice belongs to fn once it is passed. If the
+calculator is Strict, a job with a time past the end of the leap second table
+fails the run with ErrLeapSecondTableStale before anything is computed.
+*/
+func (e *BatchEngine) Run(ctx context.Context, jobs []BatchJob, fn func(BatchResult) error) error {
+	calc := e.calculator()
+	for j := range jobs {
+		job := &jobs[j]
+		var err error
+		if job.Times != nil {
+			err = calc.validateStrict(job.Times...)
+		} else if n := job.length(); n > 0 {
+			err = calc.validateStrict(job.time(n - 1))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	workers := e.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	chunkSize := e.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultBatchChunkSize
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	defer cancel()
+
+	// tasks go to the workers and, in the same order, to the loop below
+	tasks := make(chan *batchTask, workers)
+	ordered := make(chan *batchTask, 2 * workers)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(tasks)
+		defer close(ordered)
+		for j := range jobs {
+			n := jobs[j].length()
+			for offset := 0; offset < n || offset == 0; offset += chunkSize {
+				size := n - offset
+				if size > chunkSize {
+					size = chunkSize
+				}
+				task := &batchTask{
+					job: &jobs[j],
+					result: BatchResult{Job: j, Offset: offset, Positions: make([]SolarPosition, size), Done: offset + size >= n},
+					done: make(chan struct{}),
+				}
+				select {
+				case ordered <- task:
+				case <-runCtx.Done():
+					return
+				}
+				select {
+				case tasks <- task:
+				case <-runCtx.Done():
+					return
+				}
+				if n == 0 {
+					break
+				}
+			}
+		}
+	}()
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range tasks {
+				e.compute(runCtx, task)
+			}
+		}()
+	}
+
+	for task := range ordered {
+		select {
+		case <-task.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		// the task may have finished in the same instant that ctx was cancelled
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(task.result); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
+
+/*
+Computes the positions for the jobs as Run does, sending the chunks of results
+in order on the returned channel, which is closed when all have been sent. If
+ctx is cancelled the channel is closed early; check ctx.Err() to tell the
+difference. If the run fails for any other reason, such as a stale leap second
+table in Strict mode, the last result sent has the error in Err. The caller
+must either drain the channel or cancel ctx.
+*/
+func (e *BatchEngine) Stream(ctx context.Context, jobs []BatchJob) <-chan BatchResult {
+	results := make(chan BatchResult)
+	go func() {
+		defer close(results)
+		err := e.Run(ctx, jobs, func(result BatchResult) error {
+			select {
+			case results <- result:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			select {
+			case results <- BatchResult{Job: -1, Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return results
+}
@@ -0,0 +1,159 @@
+package solar
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	SunriseAltitude = float64(-0.8333) // degrees, geometric altitude of the sun's center at apparent sunrise/sunset
+	SiderealRate = float64(360.985647) // degrees of sidereal rotation per solar day
+)
+
+/*
+Times of sunrise, solar transit (local solar noon) and sunset for a single
+day at a single location. During polar day or polar night the sun does not
+cross the horizon: AlwaysUp or AlwaysDown is set and Sunrise and Sunset are
+left as zero times. Transit is always populated.
+*/
+type SunEvents struct {
+	Sunrise time.Time
+	Transit time.Time
+	Sunset time.Time
+	AlwaysUp bool
+	AlwaysDown bool
+}
+
+// limits a fraction of a day to the range [0, 1)
+func limitFraction(x float64) float64 {
+	return x - math.Floor(x)
+}
+
+func addDays(t time.Time, days float64) time.Time {
+	return t.Add(time.Duration(days * 86400 * float64(time.Second)))
+}
+
+/*
+returns the geocentric right ascension and declination of the sun, along with
+the apparent sidereal time at Greenwich, for the given instant. All values
+are in degrees; right ascension and sidereal time are limited to [0, 360).
+*/
+func getGeocentricSun(when time.Time) (float64, float64, float64) {
+	pos := SolarPosition{}
+	DefaultCalculator.computeGeocentric(&pos, when)
+	return pos.RightAscension, pos.Declination, pos.ApparentSiderealTime
+}
+
+// returns the unrefracted topocentric elevation and local hour angle (in [-180, 180)) of the sun
+func getTopocentricElevation(lat, lon, elevation float64, when time.Time) (float64, float64) {
+	topocentricSunDeclination, topocentricLocalHourAngle := GetTopocentricPosition(lat, lon, elevation, when)
+	elevationAngle := GetTopocentricElevationAngle(lat, topocentricSunDeclination, topocentricLocalHourAngle)
+	return elevationAngle, limitDegrees180(topocentricLocalHourAngle)
+}
+
+// refines an estimate of the solar transit by driving the topocentric hour angle to zero
+func refineTransit(lat, lon, elevation float64, est time.Time) time.Time {
+	t := est
+	for i := 0; i < 2; i++ {
+		_, hourAngle := getTopocentricElevation(lat, lon, elevation, t)
+		t = addDays(t, -hourAngle / SiderealRate)
+	}
+	return t
+}
+
+/*
+refines an estimate of the instant the unrefracted topocentric elevation of
+the sun crosses the given altitude, using the same correction as step 10 of
+SPA appendix A.2 but with the full topocentric position at each step.
+*/
+func refineCrossing(lat, lon, elevation float64, est time.Time, altitude float64) time.Time {
+	t := est
+	latRad := deg2rad(lat)
+	for i := 0; i < 3; i++ {
+		topocentricSunDeclination, topocentricLocalHourAngle := GetTopocentricPosition(lat, lon, elevation, t)
+		h := GetTopocentricElevationAngle(lat, topocentricSunDeclination, topocentricLocalHourAngle)
+		d := 360.0 * math.Cos(deg2rad(topocentricSunDeclination)) * math.Cos(latRad) * math.Sin(deg2rad(topocentricLocalHourAngle))
+		if d == 0 {
+			break
+		}
+		step := (h - altitude) / d
+		t = addDays(t, step)
+		if math.Abs(step) * 86400 < 0.01 {
+			break
+		}
+	}
+	return t
+}
+
+/*
+Returns the times of sunrise, transit and sunset on the given calendar date
+(year, month and day as reported by date.Date()) as observed in loc. If loc is
+nil, the location of date is used. The returned times are in loc.
+
+This follows appendix A.2 of Reda and Andreas: the geocentric right ascension
+and declination of the sun are computed for the day before, the day of and the
+day after, and interpolated to find the hour angle at transit and at the
+horizon crossings. Each estimate is then polished against the full topocentric
+position, which takes parallax at the observer's elevation into account.
+Sunrise and sunset are the moments the sun's upper limb touches a sea-level
+horizon under standard refraction (SunriseAltitude).
+*/
+func GetSunEvents(lat, lon, elevation float64, date time.Time, loc *time.Location) SunEvents {
+	if loc == nil {
+		loc = date.Location()
+	}
+	year, month, day := date.Date()
+	ref := time.Date(year, month, day, 0, 0, 0, 0, loc).UTC()
+	var alpha, delta [3]float64
+	for i := range alpha {
+		alpha[i], delta[i], _ = getGeocentricSun(ref.AddDate(0, 0, i - 1))
+	}
+	_, _, nu := getGeocentricSun(ref)
+
+	events := SunEvents{}
+	latRad := deg2rad(lat)
+	deltaRad := deg2rad(delta[1])
+	m0 := (alpha[1] - lon - nu) / 360.0
+	m := [3]float64{limitFraction(m0), 0, 0}
+	cosH0 := (math.Sin(deg2rad(SunriseAltitude)) - math.Sin(latRad) * math.Sin(deltaRad)) / (math.Cos(latRad) * math.Cos(deltaRad))
+	if cosH0 < -1 {
+		events.AlwaysUp = true
+	} else if cosH0 > 1 {
+		events.AlwaysDown = true
+	} else {
+		h0 := rad2deg(math.Acos(cosH0))
+		m[1] = limitFraction(m0 - h0 / 360.0)
+		m[2] = limitFraction(m0 + h0 / 360.0)
+	}
+
+	// interpolation differences, keeping right ascension continuous across 360
+	a := limitDegrees180(alpha[1] - alpha[0])
+	b := limitDegrees180(alpha[2] - alpha[1])
+	ad := delta[1] - delta[0]
+	bd := delta[2] - delta[1]
+	var hourAngle, altitude, dec [3]float64
+	for i, mi := range m {
+		ra := alpha[1] + mi * (a + b + (b - a) * mi) / 2.0
+		dec[i] = delta[1] + mi * (ad + bd + (bd - ad) * mi) / 2.0
+		hourAngle[i] = limitDegrees180(nu + SiderealRate * mi + lon - ra)
+		decRad := deg2rad(dec[i])
+		altitude[i] = rad2deg(math.Asin(math.Sin(latRad) * math.Sin(decRad) + math.Cos(latRad) * math.Cos(decRad) * math.Cos(deg2rad(hourAngle[i]))))
+	}
+
+	transit := m[0] - hourAngle[0] / 360.0
+	events.Transit = refineTransit(lat, lon, elevation, addDays(ref, transit)).In(loc)
+	if events.AlwaysUp || events.AlwaysDown {
+		return events
+	}
+	for i := 1; i < 3; i++ {
+		d := 360.0 * math.Cos(deg2rad(dec[i])) * math.Cos(latRad) * math.Sin(deg2rad(hourAngle[i]))
+		est := m[i] + (altitude[i] - SunriseAltitude) / d
+		t := refineCrossing(lat, lon, elevation, addDays(ref, est), SunriseAltitude).In(loc)
+		if i == 1 {
+			events.Sunrise = t
+		} else {
+			events.Sunset = t
+		}
+	}
+	return events
+}
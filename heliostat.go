@@ -0,0 +1,166 @@
+package solar
+
+/*
+Geometry for dual-axis trackers and heliostats, in local east, north, up (ENU)
+coordinates: x east, y north and z up, in meters for positions and unitless
+for directions.
+*/
+
+import (
+	"math"
+)
+
+// ENU is a position or direction in local east, north, up coordinates.
+type ENU struct {
+	East float64
+	North float64
+	Up float64
+}
+
+func (v ENU) Add(w ENU) ENU {
+	return ENU{v.East + w.East, v.North + w.North, v.Up + w.Up}
+}
+
+func (v ENU) Sub(w ENU) ENU {
+	return ENU{v.East - w.East, v.North - w.North, v.Up - w.Up}
+}
+
+func (v ENU) Scale(s float64) ENU {
+	return ENU{v.East * s, v.North * s, v.Up * s}
+}
+
+func (v ENU) Dot(w ENU) float64 {
+	return v.East * w.East + v.North * w.North + v.Up * w.Up
+}
+
+func (v ENU) Norm() float64 {
+	return math.Sqrt(v.Dot(v))
+}
+
+// returns the vector scaled to unit length
+func (v ENU) Unit() ENU {
+	return v.Scale(1 / v.Norm())
+}
+
+// returns a unit vector with the given elevation and azimuth (eastward from north) in degrees
+func GetDirection(elevation, azimuth float64) ENU {
+	elevationRad := deg2rad(elevation)
+	azimuthRad := deg2rad(azimuth)
+	return ENU{
+		East: math.Cos(elevationRad) * math.Sin(azimuthRad),
+		North: math.Cos(elevationRad) * math.Cos(azimuthRad),
+		Up: math.Sin(elevationRad),
+	}
+}
+
+// returns the elevation and azimuth (eastward from north) in degrees of a direction
+func (v ENU) Angles() (float64, float64) {
+	u := v.Unit()
+	return rad2deg(math.Asin(math.Max(-1, math.Min(1, u.Up)))), limitDegrees(rad2deg(math.Atan2(u.East, u.North)))
+}
+
+// returns the unit vector pointing at the apparent (refracted) position of the sun
+func (pos SolarPosition) SunVector() ENU {
+	return GetDirection(pos.ApparentElevation, pos.Azimuth)
+}
+
+// MountType is the arrangement of the axes of a dual-axis mount.
+type MountType int
+
+const (
+	/*
+	an azimuth axis pointing up, carrying an elevation axis: the angles are the
+	azimuth eastward from north and the elevation above the horizon
+	*/
+	AzimuthElevation MountType = iota
+	/*
+	a horizontal tilt axis pointing toward the mount's azimuth, carrying a roll
+	axis: the angles are the tilt, positive toward the right of the tilt axis,
+	and the roll, positive toward the direction of the tilt axis
+	*/
+	TiltRoll
+)
+
+/*
+DualAxisMount converts between the pointing direction of a dual-axis tracker or
+heliostat and the angles of its two axes.
+*/
+type DualAxisMount struct {
+	Type MountType
+	Azimuth float64 // degrees eastward from north of the tilt axis of a TiltRoll mount
+}
+
+// returns the horizontal unit vectors along and to the right of the tilt axis of a TiltRoll mount
+func (m DualAxisMount) tiltAxes() (ENU, ENU) {
+	return GetDirection(0, m.Azimuth), GetDirection(0, m.Azimuth + 90)
+}
+
+// returns the primary and secondary axis angles in degrees that point the mount along the given direction
+func (m DualAxisMount) Angles(direction ENU) (float64, float64) {
+	if m.Type == TiltRoll {
+		d := direction.Unit()
+		along, right := m.tiltAxes()
+		roll := rad2deg(math.Asin(math.Max(-1, math.Min(1, d.Dot(along)))))
+		tilt := rad2deg(math.Atan2(d.Dot(right), d.Up))
+		return tilt, roll
+	}
+	elevation, azimuth := direction.Angles()
+	return azimuth, elevation
+}
+
+// returns the unit direction the mount points along with the given primary and secondary axis angles in degrees
+func (m DualAxisMount) Direction(primary, secondary float64) ENU {
+	if m.Type == TiltRoll {
+		along, right := m.tiltAxes()
+		tiltRad := deg2rad(primary)
+		rollRad := deg2rad(secondary)
+		up := ENU{Up: 1}
+		return along.Scale(math.Sin(rollRad)).Add(right.Scale(math.Sin(tiltRad) * math.Cos(rollRad))).Add(up.Scale(math.Cos(tiltRad) * math.Cos(rollRad)))
+	}
+	return GetDirection(secondary, primary)
+}
+
+/*
+Aim is the pointing of a dual-axis mount: the unit normal of the collector or
+mirror, the angles of the primary and secondary axes in degrees (see
+MountType), and the cosine efficiency, the cosine of the angle between the
+sun and the normal.
+*/
+type Aim struct {
+	Normal ENU
+	Primary float64
+	Secondary float64
+	CosineEfficiency float64
+}
+
+func (m DualAxisMount) aim(normal, sun ENU) Aim {
+	a := Aim{Normal: normal, CosineEfficiency: normal.Dot(sun)}
+	a.Primary, a.Secondary = m.Angles(normal)
+	return a
+}
+
+/*
+returns the aim of a dual-axis tracker pointing straight at the sun. The
+caller should check that the sun is above the horizon.
+*/
+func (m DualAxisMount) Track(pos SolarPosition) Aim {
+	sun := pos.SunVector()
+	return m.aim(sun, sun)
+}
+
+/*
+returns the aim of a heliostat mirror at the given position that reflects
+sunlight onto the given target. The mirror normal bisects the directions to
+the sun and to the target, and the cosine efficiency is the fraction of the
+mirror's area presented to the sun.
+*/
+func (m DualAxisMount) Heliostat(pos SolarPosition, mirror, target ENU) Aim {
+	sun := pos.SunVector()
+	normal := sun.Add(target.Sub(mirror).Unit()).Unit()
+	return m.aim(normal, sun)
+}
+
+// returns the direction of a ray along incoming after reflection off a surface with the given unit normal
+func GetReflection(incoming, normal ENU) ENU {
+	return incoming.Sub(normal.Scale(2 * incoming.Dot(normal)))
+}
@@ -0,0 +1,258 @@
+package solar
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	NtpEpochOffset = int64(2208988800) // seconds from 1900-01-01 (NTP epoch) to 1970-01-01 (Unix epoch)
+	MjdUnixEpoch = float64(40587) // modified Julian day of 1970-01-01
+)
+
+var (
+	ErrLeapSecondFormat = errors.New("solar: malformed leap second file")
+	ErrLeapSecondHash = errors.New("solar: leap second file hash does not match its contents")
+	ErrLeapSecondTableInvalid = errors.New("solar: leap second table must have entries in increasing order of time")
+)
+
+// LeapSecond is the value of TAI - UTC in effect from Time on.
+type LeapSecond struct {
+	Time time.Time
+	Offset int // seconds
+}
+
+/*
+LeapSecondTable is a list of leap seconds as published by the IERS, along with
+the time until which the publisher guarantees that no further leap seconds
+will be inserted.
+*/
+type LeapSecondTable struct {
+	Entries []LeapSecond
+	Updated time.Time // zero if the file does not say
+	Expires time.Time
+}
+
+/*
+returns the table built into the package, from LeapSecondsAdjustments. It
+expires at GetLeapSecondsExpiry().
+*/
+func GetBuiltinLeapSecondTable() *LeapSecondTable {
+	table := &LeapSecondTable{Expires: GetLeapSecondsExpiry()}
+	offset := 10
+	table.Entries = append(table.Entries, LeapSecond{Time: time.Date(LeapSecondsBaseYear, time.January, 1, 0, 0, 0, 0, time.UTC), Offset: offset})
+	for i, entry := range LeapSecondsAdjustments {
+		year := LeapSecondsBaseYear + i
+		if entry[0] != 0 {
+			offset += entry[0]
+			table.Entries = append(table.Entries, LeapSecond{Time: time.Date(year, time.July, 1, 0, 0, 0, 0, time.UTC), Offset: offset})
+		}
+		if entry[1] != 0 {
+			offset += entry[1]
+			table.Entries = append(table.Entries, LeapSecond{Time: time.Date(year + 1, time.January, 1, 0, 0, 0, 0, time.UTC), Offset: offset})
+		}
+	}
+	return table
+}
+
+// Checks that the table is non-empty, in order, and expires after its last entry.
+func (table *LeapSecondTable) Validate() error {
+	if len(table.Entries) == 0 {
+		return ErrLeapSecondTableInvalid
+	}
+	for i := 1; i < len(table.Entries); i++ {
+		if !table.Entries[i].Time.After(table.Entries[i - 1].Time) {
+			return ErrLeapSecondTableInvalid
+		}
+	}
+	if !table.Expires.After(table.Entries[len(table.Entries) - 1].Time) {
+		return ErrLeapSecondTableInvalid
+	}
+	return nil
+}
+
+/*
+returns TAI - UTC in seconds at the given time. Times before the first entry
+get the first entry's offset, and times after expiry get the last entry's.
+*/
+func (table *LeapSecondTable) Offset(when time.Time) int {
+	offset := table.Entries[0].Offset
+	for _, entry := range table.Entries {
+		if when.Before(entry.Time) {
+			break
+		}
+		offset = entry.Offset
+	}
+	return offset
+}
+
+func ntpToTime(field string) (time.Time, error) {
+	ts, err := strconv.ParseInt(field, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: bad NTP timestamp %q", ErrLeapSecondFormat, field)
+	}
+	return time.Unix(ts - NtpEpochOffset, 0).UTC(), nil
+}
+
+/*
+Parses a leap-seconds.list file in the format distributed by the IERS, NIST
+and the IANA time zone database. Data lines hold an NTP timestamp (seconds
+since 1900) and the new value of TAI - UTC; the "#$" and "#@" lines hold the
+update and expiry times. If the file has a "#h" line, its SHA-1 hash of the
+update time, the expiry time and the data fields is verified.
+*/
+func ParseLeapSecondsList(r io.Reader) (*LeapSecondTable, error) {
+	table := &LeapSecondTable{}
+	var hashData bytes.Buffer
+	var hash []uint32
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	for scanner.Scan() {
+		lineno += 1
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "#$"), strings.HasPrefix(line, "#@"):
+			fields := strings.Fields(line[2:])
+			if len(fields) == 0 {
+				return nil, fmt.Errorf("%w: line %d", ErrLeapSecondFormat, lineno)
+			}
+			t, err := ntpToTime(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("%w at line %d", err, lineno)
+			}
+			if line[1] == '$' {
+				table.Updated = t
+			} else {
+				table.Expires = t
+			}
+			hashData.WriteString(fields[0])
+		case strings.HasPrefix(line, "#h"):
+			for _, word := range strings.Fields(line[2:]) {
+				v, err := strconv.ParseUint(word, 16, 32)
+				if err != nil {
+					return nil, fmt.Errorf("%w: bad hash at line %d", ErrLeapSecondFormat, lineno)
+				}
+				hash = append(hash, uint32(v))
+			}
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		default:
+			fields := strings.Fields(strings.SplitN(line, "#", 2)[0])
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("%w: line %d", ErrLeapSecondFormat, lineno)
+			}
+			t, err := ntpToTime(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("%w at line %d", err, lineno)
+			}
+			offset, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("%w: bad offset at line %d", ErrLeapSecondFormat, lineno)
+			}
+			table.Entries = append(table.Entries, LeapSecond{Time: t, Offset: offset})
+			hashData.WriteString(fields[0])
+			hashData.WriteString(fields[1])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if table.Expires.IsZero() {
+		return nil, fmt.Errorf("%w: no expiry date", ErrLeapSecondFormat)
+	}
+	if hash != nil {
+		sum := sha1.Sum(hashData.Bytes())
+		if len(hash) != 5 {
+			return nil, ErrLeapSecondHash
+		}
+		for i, word := range hash {
+			if binary.BigEndian.Uint32(sum[i * 4:]) != word {
+				return nil, ErrLeapSecondHash
+			}
+		}
+	}
+	if err := table.Validate(); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+/*
+Parses a Leap_Second.dat file as published by the IERS Earth Orientation
+Center. Data lines hold the modified Julian date, the day, month and year, and
+the new value of TAI - UTC; the expiry is taken from the "File expires on"
+comment.
+*/
+func ParseLeapSecondDat(r io.Reader) (*LeapSecondTable, error) {
+	table := &LeapSecondTable{}
+	scanner := bufio.NewScanner(r)
+	lineno := 0
+	for scanner.Scan() {
+		lineno += 1
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			idx := strings.Index(line, "File expires on")
+			if idx >= 0 {
+				t, err := time.Parse("2 January 2006", strings.TrimSpace(line[idx + len("File expires on"):]))
+				if err != nil {
+					return nil, fmt.Errorf("%w: bad expiry date at line %d", ErrLeapSecondFormat, lineno)
+				}
+				table.Expires = t
+			}
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("%w: line %d", ErrLeapSecondFormat, lineno)
+		}
+		mjd, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad MJD at line %d", ErrLeapSecondFormat, lineno)
+		}
+		offset, err := strconv.Atoi(fields[4])
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad offset at line %d", ErrLeapSecondFormat, lineno)
+		}
+		t := time.Unix(int64(math.Round((mjd - MjdUnixEpoch) * 86400)), 0).UTC()
+		table.Entries = append(table.Entries, LeapSecond{Time: t, Offset: offset})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if table.Expires.IsZero() {
+		return nil, fmt.Errorf("%w: no expiry date", ErrLeapSecondFormat)
+	}
+	if err := table.Validate(); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+/*
+Reads a leap second table from a file in either the leap-seconds.list or the
+Leap_Second.dat format, telling them apart by the "#@" expiry line that only
+the former has.
+*/
+func LoadLeapSecondTable(fn string) (*LeapSecondTable, error) {
+	data, err := os.ReadFile(fn)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Contains(data, []byte("\n#@")) {
+		return ParseLeapSecondsList(bytes.NewReader(data))
+	}
+	return ParseLeapSecondDat(bytes.NewReader(data))
+}
@@ -0,0 +1,275 @@
+package solar
+
+/*
+The position of the moon, following chapter 47 of J. Meeus, Astronomical
+Algorithms, 2nd ed., 1998, which truncates the ELP-2000/82 theory to an
+accuracy of about 10" in longitude and 4" in latitude. Its illumination follows
+chapter 48. Nutation, the sidereal time and the topocentric correction are the
+same as for the sun.
+*/
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	AstronomicalUnit = float64(149597870.7) // km
+	MoonRadiusRatio = float64(0.2725) // radius of the moon in equatorial radii of the earth
+)
+
+/*
+LunarPosition holds the outputs of the lunar position calculation for one
+observer at one instant. Angles are in degrees.
+*/
+type LunarPosition struct {
+	Time time.Time
+	JulianDay float64
+	JulianEphemerisDay float64
+	Longitude float64 // λ, geocentric ecliptic longitude, corrected for nutation
+	Latitude float64 // β, geocentric ecliptic latitude
+	Distance float64 // Δ, center of the earth to center of the moon, in km
+	EquatorialHorizontalParallax float64 // π
+	RightAscension float64 // α, geocentric
+	Declination float64 // δ, geocentric
+	LocalHourAngle float64 // H, geocentric
+	TopocentricRightAscension float64 // α'
+	TopocentricDeclination float64 // δ'
+	TopocentricLocalHourAngle float64 // H'
+	TrueElevation float64 // topocentric elevation without refraction
+	RefractionCorrection float64
+	ApparentElevation float64 // topocentric elevation with refraction
+	Zenith float64 // topocentric zenith angle
+	Azimuth float64 // topocentric azimuth measured eastward from north
+	Elongation float64 // ψ, geocentric angle between the sun and the moon
+	PhaseAngle float64 // i, angle between the sun and the earth as seen from the moon
+	IlluminatedFraction float64 // k, fraction of the disk lit by the sun
+	Phase float64 // difference of the longitudes of the moon and the sun: 0 new, 90 first quarter, 180 full, 270 last quarter
+}
+
+// the fundamental arguments of the lunar theory, in degrees, Meeus eqs. 47.1 to 47.5
+type moonArguments struct {
+	meanLongitude float64 // L'
+	meanElongation float64 // D
+	sunMeanAnomaly float64 // M
+	meanAnomaly float64 // M'
+	argumentOfLatitude float64 // F
+}
+
+func getMoonArguments(jce float64) moonArguments {
+	t := jce
+	t2 := t * t
+	t3 := t2 * t
+	t4 := t3 * t
+	return moonArguments{
+		meanLongitude: limitDegrees(218.3164477 + 481267.88123421 * t - 0.0015786 * t2 + t3 / 538841.0 - t4 / 65194000.0),
+		meanElongation: limitDegrees(297.8501921 + 445267.1114034 * t - 0.0018819 * t2 + t3 / 545868.0 - t4 / 113065000.0),
+		sunMeanAnomaly: limitDegrees(357.5291092 + 35999.0502909 * t - 0.0001536 * t2 + t3 / 24490000.0),
+		meanAnomaly: limitDegrees(134.9633964 + 477198.8675055 * t + 0.0087414 * t2 + t3 / 69699.0 - t4 / 14712000.0),
+		argumentOfLatitude: limitDegrees(93.2720950 + 483202.0175233 * t - 0.0036539 * t2 - t3 / 3526000.0 + t4 / 863310000.0),
+	}
+}
+
+/*
+returns the geocentric ecliptic longitude and latitude of the moon in degrees,
+referred to the mean equinox of date (without nutation), and its distance in
+km, for the given Julian ephemeris century.
+*/
+func GetMoonGeocentric(jce float64) (float64, float64, float64) {
+	args := getMoonArguments(jce)
+	d := deg2rad(args.meanElongation)
+	m := deg2rad(args.sunMeanAnomaly)
+	mp := deg2rad(args.meanAnomaly)
+	f := deg2rad(args.argumentOfLatitude)
+	lp := deg2rad(args.meanLongitude)
+	// the eccentricity of the earth's orbit scales the terms in M
+	e := 1 - 0.002516 * jce - 0.0000074 * jce * jce
+	eccentricity := [3]float64{1, e, e * e}
+
+	sigmaL := 0.0
+	sigmaR := 0.0
+	for _, term := range MoonLongitudeDistanceTerms {
+		arg := term[0] * d + term[1] * m + term[2] * mp + term[3] * f
+		scale := eccentricity[int(math.Abs(term[1]))]
+		sin, cos := math.Sincos(arg)
+		sigmaL += term[4] * scale * sin
+		sigmaR += term[5] * scale * cos
+	}
+	sigmaB := 0.0
+	for _, term := range MoonLatitudeTerms {
+		arg := term[0] * d + term[1] * m + term[2] * mp + term[3] * f
+		sigmaB += term[4] * eccentricity[int(math.Abs(term[1]))] * math.Sin(arg)
+	}
+
+	// the actions of Venus and Jupiter, and the flattening of the earth
+	a1 := deg2rad(119.75 + 131.849 * jce)
+	a2 := deg2rad(53.09 + 479264.290 * jce)
+	a3 := deg2rad(313.45 + 481266.484 * jce)
+	sigmaL += 3958 * math.Sin(a1) + 1962 * math.Sin(lp - f) + 318 * math.Sin(a2)
+	sigmaB += -2235 * math.Sin(lp) + 382 * math.Sin(a3) + 175 * math.Sin(a1 - f) + 175 * math.Sin(a1 + f) + 127 * math.Sin(lp - mp) - 115 * math.Sin(lp + mp)
+
+	longitude := limitDegrees(args.meanLongitude + sigmaL / 1e6)
+	latitude := sigmaB / 1e6
+	distance := 385000.56 + sigmaR / 1000
+	return longitude, latitude, distance
+}
+
+// returns the equatorial horizontal parallax of the moon in degrees at the given distance in km
+func GetMoonParallax(distance float64) float64 {
+	return rad2deg(math.Asin(EarthRadius / 1000 / distance))
+}
+
+/*
+returns the phase angle of the moon in degrees, Meeus eq. 48.3, from the
+geocentric elongation of the moon from the sun in degrees and the distances
+of the moon and the sun in km.
+*/
+func GetMoonPhaseAngle(elongation, moonDistance, sunDistance float64) float64 {
+	elongationRad := deg2rad(elongation)
+	return rad2deg(math.Atan2(sunDistance * math.Sin(elongationRad), moonDistance - sunDistance * math.Cos(elongationRad)))
+}
+
+// returns the illuminated fraction of the disk of the moon at the given phase angle in degrees, Meeus eq. 48.1
+func GetMoonIlluminatedFraction(phaseAngle float64) float64 {
+	return (1 + math.Cos(deg2rad(phaseAngle))) / 2
+}
+
+// See ComputeMoon.
+func (c *Calculator) ComputeMoon(obs Observer, when time.Time) LunarPosition {
+	sun := SolarPosition{}
+	c.computeGeocentric(&sun, when)
+	moon := LunarPosition{Time: when, JulianDay: sun.JulianDay, JulianEphemerisDay: sun.JulianEphemerisDay}
+	jce := GetJulianEphemerisCentury(moon.JulianEphemerisDay)
+	longitude, latitude, distance := GetMoonGeocentric(jce)
+	moon.Longitude = limitDegrees(longitude + sun.NutationLongitude)
+	moon.Latitude = latitude
+	moon.Distance = distance
+	moon.EquatorialHorizontalParallax = GetMoonParallax(distance)
+	// the conversion to equatorial coordinates is the same as for the sun
+	moon.RightAscension = GetGeocentricSunRightAscension(moon.Longitude, sun.TrueEclipticObliquity, moon.Latitude)
+	moon.Declination = GetGeocentricSunDeclination(moon.Longitude, sun.TrueEclipticObliquity, moon.Latitude)
+
+	top := newSite(obs).topocentric(moon.RightAscension, moon.Declination, moon.EquatorialHorizontalParallax, sun.ApparentSiderealTime)
+	moon.LocalHourAngle = top.localHourAngle
+	moon.TopocentricRightAscension = top.rightAscension
+	moon.TopocentricDeclination = top.declination
+	moon.TopocentricLocalHourAngle = top.topocentricLocalHourAngle
+	moon.TrueElevation = top.trueElevation
+	moon.RefractionCorrection = top.refractionCorrection
+	moon.ApparentElevation = top.apparentElevation
+	moon.Zenith = 90 - moon.ApparentElevation
+	moon.Azimuth = top.azimuth
+
+	// Meeus eq. 48.2, from the geocentric positions
+	sunDeclinationRad := deg2rad(sun.Declination)
+	moonDeclinationRad := deg2rad(moon.Declination)
+	cosElongation := math.Sin(sunDeclinationRad) * math.Sin(moonDeclinationRad) + math.Cos(sunDeclinationRad) * math.Cos(moonDeclinationRad) * math.Cos(deg2rad(sun.RightAscension - moon.RightAscension))
+	moon.Elongation = rad2deg(math.Acos(math.Max(-1, math.Min(1, cosElongation))))
+	moon.PhaseAngle = GetMoonPhaseAngle(moon.Elongation, moon.Distance, sun.RadiusVector * AstronomicalUnit)
+	moon.IlluminatedFraction = GetMoonIlluminatedFraction(moon.PhaseAngle)
+	moon.Phase = limitDegrees(moon.Longitude - sun.ApparentSunLongitude)
+	return moon
+}
+
+/*
+Computes the position and illumination of the moon as seen by the observer at
+the given time.
+*/
+func ComputeMoon(obs Observer, when time.Time) LunarPosition {
+	return DefaultCalculator.ComputeMoon(obs, when)
+}
+
+// Returns the refracted altitude and the azimuth of the moon in degrees.
+func (obs Observer) MoonPosition(when time.Time) (float64, float64) {
+	moon := ComputeMoon(obs, when)
+	return moon.ApparentElevation, moon.Azimuth
+}
+
+/*
+Times of moonrise, transit and moonset during a single day at a single
+location. The moon rises about 50 minutes later each day, so on some days one
+of the events does not happen and its time is left as zero. AlwaysUp or
+AlwaysDown is set when the moon does not cross the horizon at all.
+*/
+type MoonEvents struct {
+	Moonrise time.Time
+	Transit time.Time
+	Moonset time.Time
+	AlwaysUp bool
+	AlwaysDown bool
+}
+
+const moonEventStep = time.Hour // sampling interval for the search for moon events
+
+/*
+returns the unrefracted topocentric elevation of the moon's center relative to
+that at which its upper limb touches the horizon under standard refraction
+*/
+func (c *Calculator) getMoonHorizonElevation(obs Observer, when time.Time) float64 {
+	moon := c.ComputeMoon(obs, when)
+	semidiameter := rad2deg(math.Asin(MoonRadiusRatio * math.Sin(deg2rad(moon.EquatorialHorizontalParallax))))
+	return moon.TrueElevation + semidiameter + StandardRefraction
+}
+
+/*
+Returns the moonrise, transit and moonset between midnight at the start and
+end of the given calendar date (year, month and day as reported by
+date.Date()) as observed in loc. If loc is nil, the location of date is used.
+The returned times are in loc. Moonrise and moonset are the moments the
+moon's upper limb touches a sea-level horizon under standard refraction, and
+transit is the moon's upper culmination.
+*/
+func (c *Calculator) GetMoonEvents(obs Observer, date time.Time, loc *time.Location) MoonEvents {
+	if loc == nil {
+		loc = date.Location()
+	}
+	year, month, day := date.Date()
+	start := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	end := time.Date(year, month, day + 1, 0, 0, 0, 0, loc)
+	elevation := func(t time.Time) float64 {
+		return c.getMoonHorizonElevation(obs, t)
+	}
+	hourAngle := func(t time.Time) float64 {
+		return limitDegrees180(c.ComputeMoon(obs, t).TopocentricLocalHourAngle)
+	}
+
+	events := MoonEvents{AlwaysUp: true, AlwaysDown: true}
+	t0 := start
+	e0 := elevation(t0)
+	h0 := hourAngle(t0)
+	for t0.Before(end) {
+		t1 := t0.Add(moonEventStep)
+		if t1.After(end) {
+			t1 = end
+		}
+		e1 := elevation(t1)
+		h1 := hourAngle(t1)
+		if e0 > 0 || e1 > 0 {
+			events.AlwaysDown = false
+		}
+		if e0 <= 0 || e1 <= 0 {
+			events.AlwaysUp = false
+		}
+		if e0 <= 0 && e1 > 0 && events.Moonrise.IsZero() {
+			events.Moonrise = findRoot(t0, t1, elevation).In(loc)
+		} else if e0 > 0 && e1 <= 0 && events.Moonset.IsZero() {
+			events.Moonset = findRoot(t0, t1, elevation).In(loc)
+		}
+		// the hour angle passes through zero, not through the jump at 180 degrees
+		if h0 < 0 && h1 >= 0 && h1 - h0 < 180 && events.Transit.IsZero() {
+			events.Transit = findRoot(t0, t1, hourAngle).In(loc)
+		}
+		t0, e0, h0 = t1, e1, h1
+	}
+	return events
+}
+
+// See Calculator.GetMoonEvents.
+func GetMoonEvents(lat, lon, elevation float64, date time.Time, loc *time.Location) MoonEvents {
+	return DefaultCalculator.GetMoonEvents(newStandardObserver(lat, lon, elevation, nil, nil), date, loc)
+}
+
+// See GetMoonEvents.
+func (obs Observer) MoonEvents(date time.Time, loc *time.Location) MoonEvents {
+	return DefaultCalculator.GetMoonEvents(obs, date, loc)
+}
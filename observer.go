@@ -0,0 +1,163 @@
+package solar
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+const (
+	MinElevation = float64(-1000) // meters, below the lowest dry land
+	TropopauseElevation = float64(11000) // meters, upper limit of the standard atmosphere lapse rate
+)
+
+var (
+	ErrLatitudeRange = errors.New("solar: latitude must be between -90 and 90 degrees")
+	ErrLongitudeRange = errors.New("solar: longitude must be between -180 and 180 degrees")
+	ErrElevationRange = errors.New("solar: elevation must be a finite number of meters above -1000")
+	ErrTemperatureRange = errors.New("solar: temperature must be a positive number of kelvin")
+	ErrPressureRange = errors.New("solar: pressure must be a non-negative number of pascals")
+)
+
+/*
+Observer is a location on the surface of the earth along with its atmospheric
+conditions. Temperature is in Kelvin and pressure in Pascal; when nil they are
+derived from the elevation using the international standard atmosphere (see
+GetBarometricTemperature and GetBarometricPressure).
+*/
+type Observer struct {
+	Latitude float64 // degrees, north positive
+	Longitude float64 // degrees, east positive
+	Elevation float64 // meters above sea level
+	Temperature *float64
+	Pressure *float64
+}
+
+// Returns a validated Observer whose atmospheric conditions are derived from its elevation.
+func NewObserver(lat, lon, elevation float64) (Observer, error) {
+	obs := Observer{Latitude: lat, Longitude: lon, Elevation: elevation}
+	return obs, obs.Validate()
+}
+
+/*
+builds an Observer for the functions that take bare coordinates, which have
+always assumed sea-level conditions when temperature or pressure is nil.
+*/
+func newStandardObserver(lat, lon, elevation float64, temperature, pressure *float64) Observer {
+	if temperature == nil {
+		temp := StandardTemperature
+		temperature = &temp
+	}
+	if pressure == nil {
+		pres := StandardPressure
+		pressure = &pres
+	}
+	return Observer{Latitude: lat, Longitude: lon, Elevation: elevation, Temperature: temperature, Pressure: pressure}
+}
+
+// Checks that the coordinates and atmospheric conditions are physically meaningful.
+func (obs Observer) Validate() error {
+	if math.IsNaN(obs.Latitude) || obs.Latitude < -90 || obs.Latitude > 90 {
+		return ErrLatitudeRange
+	}
+	if math.IsNaN(obs.Longitude) || obs.Longitude < -180 || obs.Longitude > 180 {
+		return ErrLongitudeRange
+	}
+	if math.IsNaN(obs.Elevation) || math.IsInf(obs.Elevation, 0) || obs.Elevation < MinElevation {
+		return ErrElevationRange
+	}
+	if obs.Temperature != nil && !(*obs.Temperature > 0 && !math.IsInf(*obs.Temperature, 0)) {
+		return ErrTemperatureRange
+	}
+	if obs.Pressure != nil && !(*obs.Pressure >= 0 && !math.IsInf(*obs.Pressure, 0)) {
+		return ErrPressureRange
+	}
+	return nil
+}
+
+/*
+returns the air temperature in Kelvin of the standard atmosphere at the given
+elevation in meters, using the tropospheric lapse rate. Elevations above the
+tropopause are treated as the tropopause.
+*/
+func GetBarometricTemperature(elevation float64) float64 {
+	return StandardTemperature + EarthTemperatureLapseRate * math.Min(elevation, TropopauseElevation)
+}
+
+/*
+returns the air pressure in Pascal of the standard atmosphere at the given
+elevation in meters, from the barometric formula:
+
+	P = P0 * (T0 / (T0 + L * h)) ^ (g * M / (R * L))
+*/
+func GetBarometricPressure(elevation float64) float64 {
+	exponent := EarthGravity * EarthAtmosphereMolarMass / (AirGasConstant * EarthTemperatureLapseRate)
+	return StandardPressure * math.Pow(StandardTemperature / GetBarometricTemperature(elevation), exponent)
+}
+
+// returns the temperature in Kelvin, derived from the elevation if not given
+func (obs Observer) temperature() float64 {
+	if obs.Temperature == nil {
+		return GetBarometricTemperature(obs.Elevation)
+	}
+	return *obs.Temperature
+}
+
+// returns the pressure in Pascal, derived from the elevation if not given
+func (obs Observer) pressure() float64 {
+	if obs.Pressure == nil {
+		return GetBarometricPressure(obs.Elevation)
+	}
+	return *obs.Pressure
+}
+
+// Returns the refracted altitude and the azimuth of the sun in degrees.
+func (obs Observer) Position(when time.Time) (float64, float64) {
+	pos := Compute(obs, when)
+	return pos.ApparentElevation, pos.Azimuth
+}
+
+// Returns the refracted altitude of the sun in degrees.
+func (obs Observer) Altitude(when time.Time) float64 {
+	return Compute(obs, when).ApparentElevation
+}
+
+// Returns the azimuth of the sun in degrees, measured eastward from north.
+func (obs Observer) Azimuth(when time.Time) float64 {
+	return Compute(obs, when).Azimuth
+}
+
+// See GetSunEvents.
+func (obs Observer) SunEvents(date time.Time, loc *time.Location) SunEvents {
+	return GetSunEvents(obs.Latitude, obs.Longitude, obs.Elevation, date, loc)
+}
+
+// See GetAltitudeCrossing. Refraction above the horizon uses the observer's atmosphere.
+func (obs Observer) AltitudeCrossing(altitude float64, date time.Time, loc *time.Location, dir Direction) (time.Time, error) {
+	return getAltitudeCrossing(obs, altitude, date, loc, dir)
+}
+
+// See GetDawn.
+func (obs Observer) Dawn(date time.Time, loc *time.Location, kind Twilight) (time.Time, error) {
+	return getAltitudeCrossing(obs, float64(kind), date, loc, Rising)
+}
+
+// See GetDusk.
+func (obs Observer) Dusk(date time.Time, loc *time.Location, kind Twilight) (time.Time, error) {
+	return getAltitudeCrossing(obs, float64(kind), date, loc, Setting)
+}
+
+// See GetGoldenHour.
+func (obs Observer) GoldenHour(date time.Time, loc *time.Location) (TimeWindow, TimeWindow, error) {
+	return getAltitudeWindows(obs, GoldenHourLow, GoldenHourHigh, date, loc)
+}
+
+// See GetBlueHour.
+func (obs Observer) BlueHour(date time.Time, loc *time.Location) (TimeWindow, TimeWindow, error) {
+	return getAltitudeWindows(obs, BlueHourLow, GoldenHourLow, date, loc)
+}
+
+// Returns the estimated direct beam radiation in W/m^2 at the observer; see GetRadiationDirect.
+func (obs Observer) RadiationDirect(when time.Time) float64 {
+	return GetRadiationDirect(when, obs.Altitude(when))
+}
@@ -0,0 +1,193 @@
+package solar
+
+import (
+	"math"
+	"time"
+)
+
+/*
+SolarPosition holds every output of the solar position algorithm for one
+observer at one instant. Angles are in degrees; the symbols in the comments
+are those used by Reda and Andreas.
+*/
+type SolarPosition struct {
+	Time time.Time
+	JulianDay float64 // JD
+	JulianEphemerisDay float64 // JDE
+	HeliocentricLongitude float64 // L
+	HeliocentricLatitude float64 // B
+	RadiusVector float64 // R, sun-earth distance in astronomical units
+	GeocentricLongitude float64 // Θ
+	GeocentricLatitude float64 // β
+	NutationLongitude float64 // Δψ
+	NutationObliquity float64 // Δε
+	TrueEclipticObliquity float64 // ε
+	AberrationCorrection float64 // Δτ
+	ApparentSunLongitude float64 // λ
+	ApparentSiderealTime float64 // ν
+	RightAscension float64 // α, geocentric
+	Declination float64 // δ, geocentric
+	EquationOfTime float64 // E, in minutes
+	LocalHourAngle float64 // H, geocentric
+	EquatorialHorizontalParallax float64 // ξ
+	TopocentricRightAscension float64 // α'
+	TopocentricDeclination float64 // δ'
+	TopocentricLocalHourAngle float64 // H'
+	TrueElevation float64 // e0, topocentric elevation without refraction
+	RefractionCorrection float64 // Δe
+	ApparentElevation float64 // e, topocentric elevation with refraction
+	Zenith float64 // θ, topocentric zenith angle
+	Azimuth float64 // Φ, topocentric azimuth measured eastward from north
+}
+
+// SPA eq. A.2, the sun's mean longitude
+func GetSunMeanLongitude(jme float64) float64 {
+	jme2 := jme * jme
+	jme3 := jme2 * jme
+	jme4 := jme3 * jme
+	jme5 := jme4 * jme
+	return limitDegrees(280.4664567 + (360007.6982779 * jme) + (0.03032028 * jme2) + (jme3 / 49931.0) - (jme4 / 15300.0) - (jme5 / 2000000.0))
+}
+
+/*
+SPA eq. A.1, the equation of time in minutes: the difference between apparent
+and mean solar time, derived from the sun's position rather than the day of
+the year.
+*/
+func GetEquationOfTime(jme, geocentricSunRightAscension float64, nutation Nutation, trueEclipticObliquity float64) float64 {
+	m := GetSunMeanLongitude(jme)
+	e := 4.0 * (m - 0.0057183 - geocentricSunRightAscension + nutation.Longitude * math.Cos(deg2rad(trueEclipticObliquity)))
+	e = math.Mod(e, 1440)
+	if e > 20 {
+		e -= 1440
+	} else if e < -20 {
+		e += 1440
+	}
+	return e
+}
+
+// fills in the time-dependent (location-independent) fields of pos
+func (c *Calculator) computeGeocentric(pos *SolarPosition, when time.Time) {
+	pos.Time = when
+	computeGeocentricJulian(pos, c.GetJulianSolarDay(when), c.GetJulianEphemerisDay(when))
+}
+
+// fills in the location-independent fields of pos from the UT and TT Julian days
+func computeGeocentricJulian(pos *SolarPosition, jd, jde float64) {
+	pos.JulianDay = jd
+	pos.JulianEphemerisDay = jde
+	jce := GetJulianEphemerisCentury(pos.JulianEphemerisDay)
+	jme := GetJulianEphemerisMillenium(jce)
+	pos.HeliocentricLongitude = GetHeliocentricLongitude(jme)
+	pos.HeliocentricLatitude = GetHeliocentricLatitude(jme)
+	pos.RadiusVector = GetSunEarthDistance(jme)
+	pos.GeocentricLongitude = GetGeocentricLongitude(jme)
+	pos.GeocentricLatitude = GetGeocentricLatitude(jme)
+	nutation := GetNutationAngles(jce)
+	pos.NutationLongitude = nutation.Longitude
+	pos.NutationObliquity = nutation.Obliquity
+	pos.TrueEclipticObliquity = GetTrueEclipticObliquity(jme, nutation)
+	pos.AberrationCorrection = GetAberationCorrection(pos.RadiusVector)
+	pos.ApparentSunLongitude = GetApparentSunLongitude(pos.GeocentricLongitude, nutation, pos.AberrationCorrection)
+	pos.ApparentSiderealTime = limitDegrees(GetApparentSiderealTime(pos.JulianDay, jme, nutation))
+	pos.RightAscension = limitDegrees(GetGeocentricSunRightAscension(pos.ApparentSunLongitude, pos.TrueEclipticObliquity, pos.GeocentricLatitude))
+	pos.Declination = GetGeocentricSunDeclination(pos.ApparentSunLongitude, pos.TrueEclipticObliquity, pos.GeocentricLatitude)
+	pos.EquationOfTime = GetEquationOfTime(jme, pos.RightAscension, nutation, pos.TrueEclipticObliquity)
+	pos.EquatorialHorizontalParallax = GetEquatorialHorizontalParallax(pos.RadiusVector)
+}
+
+// the location-dependent terms of the calculation, which can be shared between times
+type site struct {
+	latitude float64
+	longitude float64
+	temperature float64
+	pressure float64
+	projectedRadialDistance float64
+	projectedAxialDistance float64
+}
+
+func newSite(obs Observer) site {
+	return site{
+		latitude: obs.Latitude,
+		longitude: obs.Longitude,
+		temperature: obs.temperature(),
+		pressure: obs.pressure(),
+		projectedRadialDistance: GetProjectedRadialDistance(obs.Elevation, obs.Latitude),
+		projectedAxialDistance: GetProjectedAxialDistance(obs.Elevation, obs.Latitude),
+	}
+}
+
+// the topocentric terms of the calculation for a body at a known geocentric position
+type topocentric struct {
+	localHourAngle float64
+	rightAscension float64
+	declination float64
+	topocentricLocalHourAngle float64
+	trueElevation float64
+	refractionCorrection float64
+	apparentElevation float64
+	azimuth float64
+}
+
+/*
+Computes the topocentric terms for a body at geocentric right ascension α and
+declination δ with equatorial horizontal parallax π, all in degrees, at the
+given apparent sidereal time. The corrections are the same for the sun and the
+moon; only the parallax differs.
+*/
+func (s site) topocentric(rightAscension, declination, parallax, siderealTime float64) topocentric {
+	top := topocentric{}
+	top.localHourAngle = GetLocalHourAngle(siderealTime, s.longitude, rightAscension)
+	parallaxRightAscension := GetParallaxSunRightAscension(s.projectedRadialDistance, parallax, top.localHourAngle, declination)
+	top.rightAscension = limitDegrees(rightAscension + parallaxRightAscension)
+	top.declination = GetTopocentricSunDeclination(declination, s.projectedRadialDistance, s.projectedAxialDistance, parallax, parallaxRightAscension, top.localHourAngle)
+	top.topocentricLocalHourAngle = limitDegrees(GetTopocentricLocalHourAngle(top.localHourAngle, parallaxRightAscension))
+	top.trueElevation = GetTopocentricElevationAngle(s.latitude, top.declination, top.topocentricLocalHourAngle)
+	top.refractionCorrection = GetRefractionCorrection(s.pressure, s.temperature, top.trueElevation)
+	top.apparentElevation = top.trueElevation + top.refractionCorrection
+	top.azimuth = GetTopocentricAzimuthAngle(top.topocentricLocalHourAngle, s.latitude, top.declination)
+	return top
+}
+
+// fills in the location-dependent fields of pos, which must already hold the geocentric values
+func (s site) computeTopocentric(pos *SolarPosition) {
+	top := s.topocentric(pos.RightAscension, pos.Declination, pos.EquatorialHorizontalParallax, pos.ApparentSiderealTime)
+	pos.LocalHourAngle = top.localHourAngle
+	pos.TopocentricRightAscension = top.rightAscension
+	pos.TopocentricDeclination = top.declination
+	pos.TopocentricLocalHourAngle = top.topocentricLocalHourAngle
+	pos.TrueElevation = top.trueElevation
+	pos.RefractionCorrection = top.refractionCorrection
+	pos.ApparentElevation = top.apparentElevation
+	pos.Zenith = 90 - pos.ApparentElevation
+	pos.Azimuth = top.azimuth
+}
+
+/*
+Computes the full position of the sun as seen by the observer at the given
+time, evaluating the ephemeris only once.
+*/
+func Compute(obs Observer, when time.Time) SolarPosition {
+	return DefaultCalculator.Compute(obs, when)
+}
+
+/*
+Computes the position of the sun from explicit UT and TT Julian days, as the
+NREL reference implementation does, bypassing the time scale conversions of
+the calculator. The Time field of the result is left zero.
+*/
+func ComputeJulian(obs Observer, jd, jde float64) SolarPosition {
+	pos := SolarPosition{}
+	computeGeocentricJulian(&pos, jd, jde)
+	newSite(obs).computeTopocentric(&pos)
+	return pos
+}
+
+/*
+Returns the angle of incidence in degrees between the sun and a surface with
+the given slope from horizontal and azimuth rotation (SPA convention: measured
+from south, east negative).
+*/
+func (pos SolarPosition) IncidenceAngle(slope, slopeOrientation float64) float64 {
+	return GetIncidenceAngle(pos.Zenith, slope, slopeOrientation, pos.Azimuth)
+}
@@ -7,10 +7,103 @@ import (
 	"time"
 )
 
+const (
+	SolarConstant = float64(1366.1) // W/m^2 at 1 AU, ASTM E-490
+	NominalSolarConstant = float64(1361) // W/m^2 at 1 AU, IAU 2015 resolution B3
+)
+
+// ExtraterrestrialMethod selects how GetExtraterrestrialRadiation accounts for the eccentricity of the earth's orbit.
+type ExtraterrestrialMethod int
+
+const (
+	ExtraterrestrialSpencer ExtraterrestrialMethod = iota // Fourier series of Spencer (1971)
+	ExtraterrestrialASCE // single cosine term of the ASCE standardized reference evapotranspiration equation
+	ExtraterrestrialNREL // sun-earth distance from the SPA ephemeris
+)
+
+// AirMassModel selects a relative air mass formula for GetAirMass.
+type AirMassModel int
+
+const (
+	AirMassKastenYoung AirMassModel = iota // Kasten and Young (1989)
+	AirMassKasten // Kasten (1966)
+	AirMassGueymard // Gueymard (1993)
+	AirMassYoung // Young (1994)
+	AirMassPickering // Pickering (2002)
+	AirMassPlaneParallel // 1 / cos(zenith), for a flat atmosphere
+)
+
+// a plain secant of the altitude, which diverges at the horizon; see GetAirMass for better models
 func GetAirMassRatio(altitudeDeg float64) float64 {
 	return 1 / math.Sin(deg2rad(altitudeDeg))
 }
 
+/*
+returns the relative optical air mass, the length of the path of sunlight
+through the atmosphere compared with the path straight up, at the given zenith
+angle in degrees. The zenith should be the apparent (refracted) one, except for
+AirMassKasten and AirMassYoung which were fitted to the true zenith. Below the
+horizon the result is NaN.
+*/
+func GetAirMass(zenith float64, model AirMassModel) float64 {
+	if zenith > 90 || math.IsNaN(zenith) {
+		return math.NaN()
+	}
+	cosZenith := math.Cos(deg2rad(zenith))
+	switch model {
+	case AirMassKasten:
+		return 1 / (cosZenith + 0.15 * math.Pow(93.885 - zenith, -1.253))
+	case AirMassGueymard:
+		return 1 / (cosZenith + 0.00176759 * zenith * math.Pow(94.37515 - zenith, -1.21563))
+	case AirMassYoung:
+		return (1.002432 * cosZenith * cosZenith + 0.148386 * cosZenith + 0.0096467) /
+			(cosZenith * cosZenith * cosZenith + 0.149864 * cosZenith * cosZenith + 0.0102963 * cosZenith + 0.000303978)
+	case AirMassPickering:
+		altitude := 90 - zenith
+		return 1 / math.Sin(deg2rad(altitude + 244 / (165 + 47 * math.Pow(altitude, 1.1))))
+	case AirMassPlaneParallel:
+		return 1 / cosZenith
+	}
+	return 1 / (cosZenith + 0.50572 * math.Pow(96.07995 - zenith, -1.6364))
+}
+
+// returns the air mass corrected for the air pressure in Pascal, relative to the standard sea-level atmosphere
+func GetAbsoluteAirMass(relativeAirMass, pressure float64) float64 {
+	return relativeAirMass * pressure / StandardPressure
+}
+
+// Returns the air mass at the observer for the given model, corrected for the observer's air pressure.
+func (obs Observer) AirMass(when time.Time, model AirMassModel) float64 {
+	return GetAbsoluteAirMass(GetAirMass(Compute(obs, when).Zenith, model), obs.pressure())
+}
+
+// returns the extraterrestrial irradiance normal to the sun in W/m^2 at the given sun-earth distance in AU
+func GetExtraterrestrialIrradiance(sunEarthDistance float64) float64 {
+	return SolarConstant / (sunEarthDistance * sunEarthDistance)
+}
+
+/*
+returns the extraterrestrial irradiance normal to the sun in W/m^2 at the given
+time, for a solar constant in W/m^2 (such as SolarConstant or
+NominalSolarConstant) scaled by the inverse square of the sun-earth distance.
+The Spencer and ASCE methods approximate the distance from the day of the
+year and agree with the ephemeris to about 0.1% and 1% respectively.
+*/
+func GetExtraterrestrialRadiation(when time.Time, solarConstant float64, method ExtraterrestrialMethod) float64 {
+	switch method {
+	case ExtraterrestrialASCE:
+		b := 2 * math.Pi * float64(when.UTC().YearDay()) / 365
+		return solarConstant * (1 + 0.033 * math.Cos(b))
+	case ExtraterrestrialNREL:
+		jme := GetJulianEphemerisMillenium(GetJulianEphemerisCentury(GetJulianEphemerisDay(when)))
+		r := GetSunEarthDistance(jme)
+		return solarConstant / (r * r)
+	}
+	b := 2 * math.Pi * float64(when.UTC().YearDay() - 1) / 365
+	return solarConstant * (1.00011 + 0.034221 * math.Cos(b) + 0.00128 * math.Sin(b) + 0.000719 * math.Cos(2 * b) + 0.000077 * math.Sin(2 * b))
+}
+
+// an empirical fit of the apparent beam irradiance for GetRadiationDirect; see GetExtraterrestrialRadiation for the true value
 func GetApparentExtraterrestrialFlux(day float64) float64 {
 	return 1160 + (75 * math.Sin(2 * math.Pi / 365 * (day - 275)))
 }
@@ -0,0 +1,121 @@
+package solar
+
+import (
+	"math"
+	"time"
+)
+
+const TropicalYear = float64(365.24219) // mean days for the apparent sun longitude to advance 360 degrees
+
+/*
+SeasonalEvent is one of the eight points of the solar year, when the apparent
+geocentric longitude of the sun reaches a multiple of 45 degrees. The events
+are numbered in the order they fall in a calendar year.
+*/
+type SeasonalEvent int
+
+const (
+	FebruaryCrossQuarter SeasonalEvent = iota // 315 degrees, Imbolc
+	MarchEquinox // 0 degrees
+	MayCrossQuarter // 45 degrees, Beltane
+	JuneSolstice // 90 degrees
+	AugustCrossQuarter // 135 degrees, Lughnasadh
+	SeptemberEquinox // 180 degrees
+	NovemberCrossQuarter // 225 degrees, Samhain
+	DecemberSolstice // 270 degrees
+)
+
+// returns the apparent sun longitude in degrees at which the event occurs
+func (event SeasonalEvent) Longitude() float64 {
+	return limitDegrees(315 + 45 * float64(event))
+}
+
+func (event SeasonalEvent) String() string {
+	switch event {
+	case FebruaryCrossQuarter:
+		return "February cross-quarter"
+	case MarchEquinox:
+		return "March equinox"
+	case MayCrossQuarter:
+		return "May cross-quarter"
+	case JuneSolstice:
+		return "June solstice"
+	case AugustCrossQuarter:
+		return "August cross-quarter"
+	case SeptemberEquinox:
+		return "September equinox"
+	case NovemberCrossQuarter:
+		return "November cross-quarter"
+	case DecemberSolstice:
+		return "December solstice"
+	}
+	return "unknown"
+}
+
+// returns the apparent sun longitude at the given time
+func (c *Calculator) getApparentSunLongitude(when time.Time) float64 {
+	pos := SolarPosition{}
+	c.computeGeocentric(&pos, when)
+	return pos.ApparentSunLongitude
+}
+
+/*
+returns the instant in UTC, during the given year, at which the apparent
+geocentric longitude of the sun (GetApparentSunLongitude) reaches the given
+value in degrees. The result is accurate to well under a second against the
+ephemeris; its accuracy in UTC depends on the calculator's delta T model.
+*/
+func (c *Calculator) GetSunLongitudeTime(year int, longitude float64) time.Time {
+	longitude = limitDegrees(longitude)
+	// the sun is near 280 degrees at the start of the year
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	when := addDays(start, limitDegrees(longitude - 280) / 360.0 * TropicalYear)
+	for attempt := 0; attempt < 2; attempt++ {
+		for i := 0; i < 10; i++ {
+			// the sun moves between 0.95 and 1.02 degrees a day, so each step gains about a factor of 30
+			days := limitDegrees180(longitude - c.getApparentSunLongitude(when)) * TropicalYear / 360.0
+			when = addDays(when, days)
+			if math.Abs(days) < 1e-3 / 86400.0 {
+				break
+			}
+		}
+		// a longitude reached in the first or last hours of the year may have been found in the next or previous one
+		if y := when.Year(); y < year {
+			when = addDays(when, TropicalYear)
+		} else if y > year {
+			when = addDays(when, -TropicalYear)
+		} else {
+			break
+		}
+	}
+	return when.Round(time.Millisecond)
+}
+
+// returns the instant in UTC at which the event occurs in the given year
+func (c *Calculator) GetSeasonalEvent(year int, event SeasonalEvent) time.Time {
+	return c.GetSunLongitudeTime(year, event.Longitude())
+}
+
+// returns the instants in UTC of the eight seasonal events of the given year, indexed by SeasonalEvent
+func (c *Calculator) GetSeasonalEvents(year int) [8]time.Time {
+	var events [8]time.Time
+	for i := range events {
+		events[i] = c.GetSeasonalEvent(year, SeasonalEvent(i))
+	}
+	return events
+}
+
+// See Calculator.GetSunLongitudeTime.
+func GetSunLongitudeTime(year int, longitude float64) time.Time {
+	return DefaultCalculator.GetSunLongitudeTime(year, longitude)
+}
+
+// See Calculator.GetSeasonalEvent.
+func GetSeasonalEvent(year int, event SeasonalEvent) time.Time {
+	return DefaultCalculator.GetSeasonalEvent(year, event)
+}
+
+// See Calculator.GetSeasonalEvents.
+func GetSeasonalEvents(year int) [8]time.Time {
+	return DefaultCalculator.GetSeasonalEvents(year)
+}
@@ -14,6 +14,20 @@ func rad2deg(rad float64) float64 {
 	return rad * 180 / math.Pi
 }
 
+// limits an angle in degrees to the range [0, 360)
+func limitDegrees(deg float64) float64 {
+	deg = math.Mod(deg, 360)
+	if deg < 0 {
+		deg += 360
+	}
+	return deg
+}
+
+// limits an angle in degrees to the range [-180, 180)
+func limitDegrees180(deg float64) float64 {
+	return limitDegrees(deg + 180) - 180
+}
+
 // returns the number of minutes to add to mean solar time to get actual solar time.
 func EquationOfTime(day float64) float64 {
 	b := 2 * math.Pi / 364.0 * (day - 81)
@@ -27,90 +41,33 @@ func GetAberationCorrection(sunEarthDistance float64) float64 {
 
 // Common calculations for altitude and azimuth
 func GetTopocentricPosition(lat, lon, elevation float64, when time.Time) (float64, float64) {
-	// location-dependent calculations
-	projectedRadialDistance := GetProjectedRadialDistance(elevation, lat)
-	projectedAxialDistance := GetProjectedAxialDistance(elevation, lat)
-
-	// time-dependent calculations
-	jd := GetJulianSolarDay(when)
-	jde := GetJulianEphemerisDay(when)
-	jce := GetJulianEphemerisCentury(jde)
-	jme := GetJulianEphemerisMillenium(jce)
-	geocentricLatitude := GetGeocentricLatitude(jme)
-	geocentricLongitude := GetGeocentricLongitude(jme)
-	sunEarthDistance := GetSunEarthDistance(jme)
-	aberrationCorrection := GetAberationCorrection(sunEarthDistance)
-	equatorialHorizontalParallax := GetEquatorialHorizontalParallax(sunEarthDistance)
-	nutation := GetNutation(jce)
-	apparentSiderealTime := GetApparentSiderealTime(jd, jme, nutation)
-	trueEclipticObliquity := GetTrueEclipticObliquity(jme, nutation)
-
-	// calculations dependent on location and time
-	apparentSunLongitude := GetApparentSunLongitude(geocentricLongitude, nutation, aberrationCorrection)
-	geocentricSunRightAscension := GetGeocentricSunRightAscension(apparentSunLongitude, trueEclipticObliquity, geocentricLatitude)
-	geocentricSunDeclination := GetGeocentricSunDeclination(apparentSunLongitude, trueEclipticObliquity, geocentricLatitude)
-	localHourAngle := GetLocalHourAngle(apparentSiderealTime, lon, geocentricSunRightAscension)
-	parallaxSunRightAscension := GetParallaxSunRightAscension(projectedRadialDistance, equatorialHorizontalParallax, localHourAngle, geocentricSunDeclination)
-	topocentricLocalHourAngle := GetTopocentricLocalHourAngle(localHourAngle, parallaxSunRightAscension)
-	topocentricSunDeclination := GetTopocentricSunDeclination(geocentricSunDeclination, projectedAxialDistance, equatorialHorizontalParallax, parallaxSunRightAscension, localHourAngle)
-	return topocentricSunDeclination, topocentricLocalHourAngle
+	pos := Compute(newStandardObserver(lat, lon, elevation, nil, nil), when)
+	return pos.TopocentricDeclination, pos.TopocentricLocalHourAngle
 }
 
 /*
 Given location, time and atmospheric conditions
 temperature in Kelvin and pressure in Pascal
 	
-returns (azimuth, altitude) of sun in degrees.
+returns (altitude, azimuth) of sun in degrees.
 
-Same as a combination of get_azimuth and get_altitude
+Same as a combination of GetAltitude and GetAzimuth. Use Compute to get
+every intermediate value of the calculation.
 */
 func GetPosition(lat, lon, elevation float64, when time.Time, temperature, pressure *float64) (float64, float64) {
-	var temp, pres float64
-	if temperature == nil {
-		temp = StandardTemperature
-	} else {
-		temp = *temperature
-	}
-	if pressure == nil {
-		pres = StandardPressure
-	} else {
-		pres = *pressure
-	}
-	topocentricSunDeclination, topocentricLocalHourAngle := GetTopocentricPosition(lat, lon, elevation, when)
-	topocentricElevationAngle := GetTopocentricElevationAngle(lat, topocentricSunDeclination, topocentricLocalHourAngle)
-	refractionCorrection := GetRefractionCorrection(pres, temp, topocentricElevationAngle)
-	altitudeDeg := topocentricElevationAngle + refractionCorrection
-	azimuthDeg := GetTopocentricAzimuthAngle(topocentricLocalHourAngle, lat, topocentricSunDeclination)
-	return altitudeDeg, azimuthDeg
+	pos := Compute(newStandardObserver(lat, lon, elevation, temperature, pressure), when)
+	return pos.ApparentElevation, pos.Azimuth
 }
 
 /*
-See also the faster, but less accurate, get_altitude_fast()
+See also the faster, but less accurate, GetAltitudeFast()
 temperature in Kelvin and pressure in Pascal
 */
 func GetAltitude(lat, lon, elevation float64, when time.Time, temperature, pressure *float64) float64 {
-	var temp, pres float64
-	if temperature == nil {
-		temp = StandardTemperature
-	} else {
-		temp = *temperature
-	}
-	if pressure == nil {
-		pres = StandardPressure
-	} else {
-		pres = *pressure
-	}
-	topocentricSunDeclination, topocentricLocalHourAngle := GetTopocentricPosition(lat, lon, elevation, when)
-	topocentricElevationAngle := GetTopocentricElevationAngle(lat, topocentricSunDeclination, topocentricLocalHourAngle)
-	refractionCorrection := GetRefractionCorrection(pres, temp, topocentricElevationAngle)
-	return topocentricElevationAngle + refractionCorrection
+	pos := Compute(newStandardObserver(lat, lon, elevation, temperature, pressure), when)
+	return pos.ApparentElevation
 }
 
-
-
-
-
-
 func GetAltitudeFast(latitudeDeg, longitudeDeg float64, when time.Time) float64 {
 	// expect 19 degrees for GetAltitude(42.364908,-71.112828,0,time.Date(2007, time.February, 18, 20, 13, 1, 130320000),nil, nil)
 	day := when.YearDay()
@@ -123,17 +80,16 @@ func GetAltitudeFast(latitudeDeg, longitudeDeg float64, when time.Time) float64
 }
 
 
-func GetApparentSiderealTime(jd, jme float64, nutation map[string]float64) float64 {
-	return GetMeanSiderealTime(jd) + nutation["longitude"] * math.Cos(GetTrueEclipticObliquity(jme, nutation))
+func GetApparentSiderealTime(jd, jme float64, nutation Nutation) float64 {
+	return GetMeanSiderealTime(jd) + nutation.Longitude * math.Cos(deg2rad(GetTrueEclipticObliquity(jme, nutation)))
 }
 
-func GetApparentSunLongitude(geocentricLongitude float64, nutation map[string]float64, abCorrection float64) float64 {
-	return geocentricLongitude + nutation["longitude"] + abCorrection
+func GetApparentSunLongitude(geocentricLongitude float64, nutation Nutation, abCorrection float64) float64 {
+	return geocentricLongitude + nutation.Longitude + abCorrection
 }
 
 func GetAzimuth(latitudeDeg, longitudeDeg, elevation float64, when time.Time) float64 {
-	topocentricSunDeclination, topocentricLocalHourAngle := GetTopocentricPosition(latitudeDeg, longitudeDeg, elevation, when)
-	return GetTopocentricAzimuthAngle(topocentricLocalHourAngle, latitudeDeg, topocentricSunDeclination)
+	return newStandardObserver(latitudeDeg, longitudeDeg, elevation, nil, nil).Azimuth(when)
 }
 
 func GetAzimuthFast(latitudeDeg, longitudeDeg float64, when time.Time) float64 {
@@ -184,7 +140,7 @@ func GetDeclination(day float64) float64 {
 }
 
 func GetEquatorialHorizontalParallax(sunEarthDistance float64) float64 {
-	return 8.794 / (3600 / sunEarthDistance)
+	return 8.794 / (3600 * sunEarthDistance)
 }
 
 func GetFlattenedLatitude(latitude float64) float64 {
@@ -198,7 +154,7 @@ func GetGeocentricLatitude(jme float64) float64 {
 }
 
 func GetGeocentricLongitude(jme float64) float64 {
-	return math.Mod(GetHeliocentricLongitude(jme) + 180, 360)
+	return limitDegrees(GetHeliocentricLongitude(jme) + 180)
 }
 
 func GetGeocentricSunDeclination(apparentSunLongitude, trueEclipticObliquity, geocentricLatitude float64) float64 {
@@ -220,7 +176,7 @@ func GetGeocentricSunRightAscension(apparentSunLongitude, trueEclipticObliquity,
 	b := math.Tan(geocentricLatitudeRad) * math.Sin(trueEclipticObliquityRad)
 	c := math.Cos(apparentSunLongitudeRad)
 	alpha := math.Atan2((a - b),  c)
-	return math.Mod(rad2deg(alpha), 360)
+	return limitDegrees(rad2deg(alpha))
 }
 
 // Heliocentric functions calculate angles relative to the center of the sun.
@@ -229,7 +185,7 @@ func GetHeliocentricLatitude(jme float64) float64 {
 }
 
 func GetHeliocentricLongitude(jme float64) float64 {
-	return math.Mod(rad2deg(GetCoeff(jme, HeliocentricLongitudeCoeffs) / 1e8), 360)
+	return limitDegrees(rad2deg(GetCoeff(jme, HeliocentricLongitudeCoeffs) / 1e8))
 }
 
 func GetHourAngle(when time.Time, longitudeDeg float64) float64 {
@@ -246,54 +202,65 @@ func GetIncidenceAngle(topocentricZenithAngle, slope, slopeOrientation, topocent
 }
 
 func GetLocalHourAngle(apparentSiderealTime, longitude, geocentricSunRightAscension float64) float64 {
-	return math.Mod(apparentSiderealTime + longitude - geocentricSunRightAscension, 360)
+	return limitDegrees(apparentSiderealTime + longitude - geocentricSunRightAscension)
 }
 
-// This function doesn't agree with Andreas and Reda as well as it should. Works to ~5 sig figs in current unit test
+// mean sidereal time at Greenwich in degrees, SPA eq. 12
 func GetMeanSiderealTime(jd float64) float64 {
 	jc := GetJulianCentury(jd)
-	siderealTime :=  280.46061837 + (360.98564736629 * (jd - 2451545.0)) + 0.000387933 * jc * jc * (1 - jc / 38710000)
-	return math.Mod(siderealTime, 360)
+	siderealTime := 280.46061837 + (360.98564736629 * (jd - 2451545.0)) + jc * jc * (0.000387933 - jc / 38710000)
+	return limitDegrees(siderealTime)
 }
 
-func sum(vals []float64) float64 {
-	s := float64(0)
-	for _, v := range vals {
-		s += v
-	}
-	return s
+// Nutation is the nutation of the earth's axis in degrees, SPA eqs. 22 and 23.
+type Nutation struct {
+	Longitude float64 // Δψ
+	Obliquity float64 // Δε
 }
 
-func GetNutation(jce float64) map[string]float64 {
-	abcd := NutationCoefficients
-	nutationLong := []float64{}
-	nutationOblique := []float64{}
-	p := GetAberrationCoeffs()
-	// order here matters
-	keys := []string{
-		"MeanElongationOfMoon",
-		"MeanAnomalyOfSun",
-		"MeanAnomalyOfMoon",
-		"ArgumentOfLatitudeOfMoon",
-		"LongitudeOfAscendingNode",
-	}
-	x := make([]float64, len(keys))
-	for i, k := range keys {
-		x[i] = p[k](jce)
+/*
+returns the mean elongation of the moon, the mean anomalies of the sun and the
+moon, the moon's argument of latitude and the longitude of the ascending node
+of the moon's orbit in degrees, SPA eqs. 15 to 19, in the order of the columns
+of AberrationSinTerms.
+*/
+func getNutationArguments(jce float64) [5]float64 {
+	jce2 := jce * jce
+	jce3 := jce2 * jce
+	return [5]float64{
+		297.85036 + 445267.111480 * jce - 0.0019142 * jce2 + jce3 / 189474.0,
+		357.52772 + 35999.050340 * jce - 0.0001603 * jce2 - jce3 / 300000.0,
+		134.96298 + 477198.867398 * jce + 0.0086972 * jce2 + jce3 / 56250.0,
+		93.27191 + 483202.017538 * jce - 0.0036825 * jce2 + jce3 / 327270.0,
+		125.04452 - 1934.136261 * jce + 0.0020708 * jce2 + jce3 / 450000.0,
 	}
-	y := AberrationSinTerms
-	for i := range abcd {
-		sigmaxy := 0.0
-		for j := range x {
-			sigmaxy += x[j] * y[i][j]
-		}
-		nutationLong = append(nutationLong, (abcd[i][0] + (abcd[i][1] * jce)) * math.Sin(deg2rad(sigmaxy)))
-		nutationOblique = append(nutationOblique, (abcd[i][2] + (abcd[i][3] * jce)) * math.Cos(deg2rad(sigmaxy)))
+}
+
+// returns the nutation in longitude and obliquity for the given Julian ephemeris century
+func GetNutationAngles(jce float64) Nutation {
+	x := getNutationArguments(jce)
+	var longitude, obliquity float64
+	for i, y := range AberrationSinTerms {
+		sigmaxy := x[0] * y[0] + x[1] * y[1] + x[2] * y[2] + x[3] * y[3] + x[4] * y[4]
+		abcd := &NutationCoefficients[i]
+		sin, cos := math.Sincos(deg2rad(sigmaxy))
+		longitude += (abcd[0] + abcd[1] * jce) * sin
+		obliquity += (abcd[2] + abcd[3] * jce) * cos
 	}
 	// 36000000 scales from 0.0001 arcseconds to degrees
+	return Nutation{longitude / 36000000.0, obliquity / 36000000.0}
+}
+
+/*
+returns the nutation as a map with the keys "longitude" and "obliquity".
+
+Deprecated: use GetNutationAngles, which does not allocate.
+*/
+func GetNutation(jce float64) map[string]float64 {
+	n := GetNutationAngles(jce)
 	return map[string]float64{
-		"longitude": sum(nutationLong) / 36000000.0,
-		"obliquity": sum(nutationOblique) / 36000000.0,
+		"longitude": n.Longitude,
+		"obliquity": n.Obliquity,
 	}
 }
 
@@ -329,7 +296,7 @@ func GetRefractionCorrection(pressure, temperature, topocentricElevationAngle fl
 	// http://www.nrel.gov/midc/spa/
 
 	sunRadius := 0.26667
-	atmosRefract := 0.5667
+	atmosRefract := StandardRefraction
 	tea := topocentricElevationAngle
 
 	// Approximation only valid if sun is not well below horizon
@@ -348,10 +315,12 @@ func GetRefractionCorrection(pressure, temperature, topocentricElevationAngle fl
 
 /*
 returns solar time in hours for the specified longitude and time,
-accurate only to the nearest minute.
+accurate only to the nearest minute. See GetLocalApparentTime for an accurate
+value.
 */
 func GetSolarTime(longitudeDeg float64, when time.Time) float64 {
-	return float64(when.Hour()) * 60 + float64(when.Minute()) + 4 * longitudeDeg + EquationOfTime(float64(when.YearDay())) / 60
+	utc := when.UTC()
+	return (float64(utc.Hour()) * 60 + float64(utc.Minute()) + 4 * longitudeDeg + EquationOfTime(float64(utc.YearDay()))) / 60
 }
 
 // Topocentric functions calculate angles relative to a location on the surface of the earth.
@@ -377,14 +346,15 @@ func GetTopocentricLocalHourAngle(localHourAngle, parallaxSunRightAscension floa
 	return localHourAngle - parallaxSunRightAscension
 }
 
-func GetTopocentricSunDeclination(geocentricSunDeclination, projectedAxialDistance, equatorialHorizontalParallax, parallaxSunRightAscension, localHourAngle float64) float64 {
+func GetTopocentricSunDeclination(geocentricSunDeclination, projectedRadialDistance, projectedAxialDistance, equatorialHorizontalParallax, parallaxSunRightAscension, localHourAngle float64) float64 {
 	gsdRad := deg2rad(geocentricSunDeclination)
+	prd := projectedRadialDistance
 	pad := projectedAxialDistance
 	ehpRad := deg2rad(equatorialHorizontalParallax)
 	psraRad := deg2rad(parallaxSunRightAscension)
 	lhaRad := deg2rad(localHourAngle)
 	a := (math.Sin(gsdRad) - pad * math.Sin(ehpRad)) * math.Cos(psraRad)
-	b := math.Cos(gsdRad) - (pad * math.Sin(ehpRad) * math.Cos(lhaRad))
+	b := math.Cos(gsdRad) - (prd * math.Sin(ehpRad) * math.Cos(lhaRad))
 	return rad2deg(math.Atan2(a, b))
 }
 
@@ -400,7 +370,7 @@ func GetTopocentricZenithAngle(latitude, topocentricSunDeclination, topocentricL
 	return 90 - tea - GetRefractionCorrection(pressure, temperature, tea)
 }
 
-func GetTrueEclipticObliquity(jme float64, nutation map[string]float64) float64 {
+func GetTrueEclipticObliquity(jme float64, nutation Nutation) float64 {
 	u := jme / 10.0
 	u2 := u * u
 	u3 := u2 * u
@@ -412,7 +382,7 @@ func GetTrueEclipticObliquity(jme float64, nutation map[string]float64) float64
 	u9 := u8 * u
 	u10 := u9 * u
 	meanObliquity := 84381.448 - (4680.93 * u) - (1.55 * u2) + (1999.25 * u3) - (51.38 * u4) -(249.67 * u5) - (39.05 * u6) + (7.12 * u7) + (27.87 * u8) + (5.79 * u9) + (2.45 * u10)
-	return (meanObliquity / 3600.0) + nutation["obliquity"]
+	return (meanObliquity / 3600.0) + nutation.Obliquity
 }
 
 
@@ -0,0 +1,105 @@
+package solar
+
+/*
+Local solar time. Local mean time runs four minutes ahead of UT1, the time
+kept by the rotation of the earth, for each degree of longitude east of
+Greenwich, and local apparent time, the time shown by a sundial, differs from
+it by the equation of time. Both are measured from local midnight, and are
+independent of the location of the time.Time given. UT1 stays within a second
+of UTC; it comes from the Calculator's DUT1 or delta T model.
+*/
+
+import (
+	"time"
+)
+
+// returns the time shift of local mean time from UTC at the given longitude in degrees
+func getLongitudeOffset(longitude float64) time.Duration {
+	return time.Duration(longitude * 240 * float64(time.Second))
+}
+
+// returns the time since the start of the day in UT1
+func (c *Calculator) getUT1TimeOfDay(when time.Time) time.Duration {
+	utc := when.UTC()
+	year, month, day := utc.Date()
+	ut1 := time.Duration(c.GetTimeScaleOffset(when, TimeScaleUT1) * float64(time.Second))
+	return utc.Sub(time.Date(year, month, day, 0, 0, 0, 0, time.UTC)) + ut1
+}
+
+// limits a time of day to the range [0, 24h)
+func limitTimeOfDay(d time.Duration) time.Duration {
+	d %= 24 * time.Hour
+	if d < 0 {
+		d += 24 * time.Hour
+	}
+	return d
+}
+
+// returns local mean time at the given longitude in degrees, as the time since local mean midnight
+func (c *Calculator) GetLocalMeanTime(longitude float64, when time.Time) time.Duration {
+	return limitTimeOfDay(c.getUT1TimeOfDay(when) + getLongitudeOffset(longitude))
+}
+
+/*
+returns the instant on the given date (year, month and day as reported by
+date.Date()) at which local mean time at the given longitude is meanTime, the
+inverse of GetLocalMeanTime. The returned time is in date's location.
+*/
+func (c *Calculator) GetTimeFromLocalMeanTime(longitude float64, date time.Time, meanTime time.Duration) time.Time {
+	year, month, day := date.Date()
+	midnight := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	when := midnight.Add(meanTime - getLongitudeOffset(longitude))
+	ut1 := time.Duration(c.GetTimeScaleOffset(when, TimeScaleUT1) * float64(time.Second))
+	return when.Add(-ut1).In(date.Location())
+}
+
+// returns the equation of time, SPA eq. A.1, as a duration
+func (c *Calculator) getEquationOfTime(when time.Time) time.Duration {
+	pos := SolarPosition{}
+	c.computeGeocentric(&pos, when)
+	return time.Duration(pos.EquationOfTime * float64(time.Minute))
+}
+
+/*
+returns local apparent time at the given longitude in degrees, as the time
+since local apparent midnight: 12:00 is the moment the sun crosses the
+meridian.
+*/
+func (c *Calculator) GetLocalApparentTime(longitude float64, when time.Time) time.Duration {
+	return limitTimeOfDay(c.GetLocalMeanTime(longitude, when) + c.getEquationOfTime(when))
+}
+
+/*
+returns the instant on the given date (year, month and day as reported by
+date.Date()) at which local apparent time at the given longitude is
+apparentTime, the inverse of GetLocalApparentTime. The returned time is in
+date's location.
+*/
+func (c *Calculator) GetTimeFromLocalApparentTime(longitude float64, date time.Time, apparentTime time.Duration) time.Time {
+	when := c.GetTimeFromLocalMeanTime(longitude, date, apparentTime)
+	// the equation of time changes by under 30 seconds a day, so this converges at once
+	for i := 0; i < 2; i++ {
+		when = c.GetTimeFromLocalMeanTime(longitude, date, apparentTime - c.getEquationOfTime(when))
+	}
+	return when
+}
+
+// See Calculator.GetLocalMeanTime.
+func GetLocalMeanTime(longitude float64, when time.Time) time.Duration {
+	return DefaultCalculator.GetLocalMeanTime(longitude, when)
+}
+
+// See Calculator.GetTimeFromLocalMeanTime.
+func GetTimeFromLocalMeanTime(longitude float64, date time.Time, meanTime time.Duration) time.Time {
+	return DefaultCalculator.GetTimeFromLocalMeanTime(longitude, date, meanTime)
+}
+
+// See Calculator.GetLocalApparentTime.
+func GetLocalApparentTime(longitude float64, when time.Time) time.Duration {
+	return DefaultCalculator.GetLocalApparentTime(longitude, when)
+}
+
+// See Calculator.GetTimeFromLocalApparentTime.
+func GetTimeFromLocalApparentTime(longitude float64, date time.Time, apparentTime time.Duration) time.Time {
+	return DefaultCalculator.GetTimeFromLocalApparentTime(longitude, date, apparentTime)
+}
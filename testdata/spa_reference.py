@@ -0,0 +1,208 @@
+#!/usr/bin/env python3
+"""
+Generates spa_reference.csv, the reference cases used by TestSPAReference.
+
+The calculation is a line by line transcription of spa.c, the NREL reference
+implementation of the Solar Position Algorithm (Reda and Andreas, NREL/TP-560-34302,
+revised January 2008), using the same order of operations and the same
+constants. The periodic term tables are read from ../constants.go, after
+checking their sizes against spa.c; the published example in
+TestSPAExample checks their values.
+
+The only deviation from spa.c is that temperatures are in kelvin and pressures
+in pascals, as the Go package takes them, so the refraction term uses
+283 / T rather than 283 / (273 + T).
+
+Usage: python3 spa_reference.py > spa_reference.csv
+"""
+
+import math
+import os
+import random
+import re
+import sys
+
+SUN_RADIUS = 0.26667
+ATMOS_REFRACT = 0.5667
+
+
+def load_tables():
+    path = os.path.join(os.path.dirname(os.path.abspath(__file__)), "..", "constants.go")
+    src = open(path).read()
+    tables = {}
+    for name in ("AberrationSinTerms", "NutationCoefficients", "HeliocentricLongitudeCoeffs", "HeliocentricLatitudeCoeffs", "SunEarthDistanceCoeffs"):
+        m = re.search(r"var %s = [^{]*\{(.*?)\n\}" % name, src, re.S)
+        body = re.sub(r"//[^\n]*", "", m.group(1))
+        body = re.sub(r"(\[\])*\[\d\]float64", "", body)
+        tables[name] = eval("[" + body.replace("{", "[").replace("}", "]") + "]")
+    # sizes from spa.c
+    assert [len(t) for t in tables["HeliocentricLongitudeCoeffs"]] == [64, 34, 20, 7, 3, 1]
+    assert [len(t) for t in tables["HeliocentricLatitudeCoeffs"]] == [5, 2]
+    assert [len(t) for t in tables["SunEarthDistanceCoeffs"]] == [40, 10, 6, 2, 1]
+    assert len(tables["AberrationSinTerms"]) == 63
+    assert len(tables["NutationCoefficients"]) == 63
+    return tables
+
+
+TABLES = load_tables()
+
+
+def rad2deg(r):
+    return (180.0 / math.pi) * r
+
+
+def deg2rad(d):
+    return (math.pi / 180.0) * d
+
+
+def limit_degrees(degrees):
+    degrees /= 360.0
+    limited = 360.0 * (degrees - math.floor(degrees))
+    if limited < 0:
+        limited += 360.0
+    return limited
+
+
+def limit_minutes(minutes):
+    limited = minutes
+    if limited < -20.0:
+        limited += 1440.0
+    elif limited > 20.0:
+        limited -= 1440.0
+    return limited
+
+
+def third_order_polynomial(a, b, c, d, x):
+    return ((a * x + b) * x + c) * x + d
+
+
+def earth_values(terms, jme):
+    total = 0.0
+    for i, rows in enumerate(terms):
+        s = 0.0
+        for a, b, c in rows:
+            s += a * math.cos(b + c * jme)
+        total += s * math.pow(jme, i)
+    return total / 1.0e8
+
+
+def nutation(jce):
+    x = [
+        third_order_polynomial(1.0 / 189474.0, -0.0019142, 445267.11148, 297.85036, jce),
+        third_order_polynomial(-1.0 / 300000.0, -0.0001603, 35999.05034, 357.52772, jce),
+        third_order_polynomial(1.0 / 56250.0, 0.0086972, 477198.867398, 134.96298, jce),
+        third_order_polynomial(1.0 / 327270.0, -0.0036825, 483202.017538, 93.27191, jce),
+        third_order_polynomial(1.0 / 450000.0, 0.0020708, -1934.136261, 125.04452, jce),
+    ]
+    sum_psi = 0.0
+    sum_epsilon = 0.0
+    for y, pe in zip(TABLES["AberrationSinTerms"], TABLES["NutationCoefficients"]):
+        xy_term_sum = deg2rad(sum(x[j] * y[j] for j in range(5)))
+        sum_psi += (pe[0] + jce * pe[1]) * math.sin(xy_term_sum)
+        sum_epsilon += (pe[2] + jce * pe[3]) * math.cos(xy_term_sum)
+    return sum_psi / 36000000.0, sum_epsilon / 36000000.0
+
+
+def spa(jd, delta_t, latitude, longitude, elevation, pressure, temperature, slope, azm_rotation):
+    jc = (jd - 2451545.0) / 36525.0
+    jde = jd + delta_t / 86400.0
+    jce = (jde - 2451545.0) / 36525.0
+    jme = jce / 10.0
+
+    l = limit_degrees(rad2deg(earth_values(TABLES["HeliocentricLongitudeCoeffs"], jme)))
+    b = rad2deg(earth_values(TABLES["HeliocentricLatitudeCoeffs"], jme))
+    r = earth_values(TABLES["SunEarthDistanceCoeffs"], jme)
+
+    theta = l + 180.0
+    if theta >= 360.0:
+        theta -= 360.0
+    beta = -b
+
+    del_psi, del_epsilon = nutation(jce)
+
+    u = jme / 10.0
+    epsilon0 = 84381.448 + u * (-4680.93 + u * (-1.55 + u * (1999.25 + u * (-51.38 + u * (-249.67 +
+        u * (-39.05 + u * (7.12 + u * (27.87 + u * (5.79 + u * 2.45)))))))))
+    epsilon = del_epsilon + epsilon0 / 3600.0
+
+    del_tau = -20.4898 / (3600.0 * r)
+    lamda = theta + del_psi + del_tau
+
+    nu0 = limit_degrees(280.46061837 + 360.98564736629 * (jd - 2451545.0) +
+        jc * jc * (0.000387933 - jc / 38710000.0))
+    nu = nu0 + del_psi * math.cos(deg2rad(epsilon))
+
+    lamda_rad = deg2rad(lamda)
+    epsilon_rad = deg2rad(epsilon)
+    beta_rad = deg2rad(beta)
+    alpha = limit_degrees(rad2deg(math.atan2(math.sin(lamda_rad) * math.cos(epsilon_rad) -
+        math.tan(beta_rad) * math.sin(epsilon_rad), math.cos(lamda_rad))))
+    delta = rad2deg(math.asin(math.sin(beta_rad) * math.cos(epsilon_rad) +
+        math.cos(beta_rad) * math.sin(epsilon_rad) * math.sin(lamda_rad)))
+
+    h = limit_degrees(nu + longitude - alpha)
+    xi = 8.794 / (3600.0 * r)
+
+    lat_rad = deg2rad(latitude)
+    xi_rad = deg2rad(xi)
+    h_rad = deg2rad(h)
+    delta_rad = deg2rad(delta)
+    uu = math.atan(0.99664719 * math.tan(lat_rad))
+    y = 0.99664719 * math.sin(uu) + elevation * math.sin(lat_rad) / 6378140.0
+    x = math.cos(uu) + elevation * math.cos(lat_rad) / 6378140.0
+    del_alpha_rad = math.atan2(-x * math.sin(xi_rad) * math.sin(h_rad),
+        math.cos(delta_rad) - x * math.sin(xi_rad) * math.cos(h_rad))
+    delta_prime = rad2deg(math.atan2((math.sin(delta_rad) - y * math.sin(xi_rad)) * math.cos(del_alpha_rad),
+        math.cos(delta_rad) - x * math.sin(xi_rad) * math.cos(h_rad)))
+    del_alpha = rad2deg(del_alpha_rad)
+    h_prime = h - del_alpha
+
+    delta_prime_rad = deg2rad(delta_prime)
+    h_prime_rad = deg2rad(h_prime)
+    e0 = rad2deg(math.asin(math.sin(lat_rad) * math.sin(delta_prime_rad) +
+        math.cos(lat_rad) * math.cos(delta_prime_rad) * math.cos(h_prime_rad)))
+    del_e = 0.0
+    if e0 >= -1.0 * (SUN_RADIUS + ATMOS_REFRACT):
+        del_e = (pressure / 101000.0) * (283.0 / temperature) * 1.02 / (60.0 * math.tan(deg2rad(e0 + 10.3 / (e0 + 5.11))))
+    e = e0 + del_e
+    zenith = 90.0 - e
+
+    azimuth_astro = limit_degrees(rad2deg(math.atan2(math.sin(h_prime_rad),
+        math.cos(h_prime_rad) * math.sin(lat_rad) - math.tan(delta_prime_rad) * math.cos(lat_rad))))
+    azimuth = limit_degrees(azimuth_astro + 180.0)
+
+    zenith_rad = deg2rad(zenith)
+    slope_rad = deg2rad(slope)
+    incidence = rad2deg(math.acos(math.cos(zenith_rad) * math.cos(slope_rad) +
+        math.sin(slope_rad) * math.sin(zenith_rad) * math.cos(deg2rad(azimuth_astro) - deg2rad(azm_rotation))))
+
+    m = limit_degrees(280.4664567 + 360007.6982779 * jme + 0.03032028 * jme ** 2 +
+        jme ** 3 / 49931.0 - jme ** 4 / 15300.0 - jme ** 5 / 2000000.0)
+    eot = limit_minutes(4.0 * (m - 0.0057183 - alpha + del_psi * math.cos(epsilon_rad)))
+
+    return alpha, delta, eot, zenith, azimuth, incidence
+
+
+def main():
+    rng = random.Random(20031017)
+    out = sys.stdout
+    out.write("# generated by spa_reference.py; do not edit\n")
+    out.write("jd,delta_t,latitude,longitude,elevation,pressure,temperature,slope,azm_rotation,alpha,delta,eot,zenith,azimuth,incidence\n")
+    for i in range(3000):
+        # years -2000 to 6000, the validity range of the algorithm
+        jd = round(rng.uniform(991000.0, 3912000.0), 6)
+        delta_t = round(rng.uniform(-8000.0, 8000.0), 3)
+        latitude = round(rng.uniform(-89.9, 89.9), 6)
+        longitude = round(rng.uniform(-180.0, 180.0), 6)
+        elevation = round(rng.uniform(-400.0, 6000.0), 2)
+        pressure = round(rng.uniform(50000.0, 105000.0), 1)
+        temperature = round(rng.uniform(230.0, 320.0), 2)
+        slope = round(rng.uniform(0.0, 90.0), 3)
+        azm_rotation = round(rng.uniform(-180.0, 180.0), 3)
+        row = [jd, delta_t, latitude, longitude, elevation, pressure, temperature, slope, azm_rotation]
+        row.extend(spa(*row))
+        out.write(",".join(repr(v) for v in row) + "\n")
+
+
+if __name__ == "__main__":
+    main()
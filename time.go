@@ -1,7 +1,6 @@
 package solar
 
 import (
-	"log"
 	"time"
 )
 
@@ -73,25 +72,29 @@ var LeapSecondsAdjustments = [][2]int{
 	[2]int{0, 0}, // 202r
 }
 
+/*
+returns the number of leap seconds (TAI - UTC) in effect at the given time.
+Times past GetLeapSecondsExpiry() get the last known value, and are reported
+to the diagnostics of DefaultCalculator.
+*/
 func GetLeapSeconds(when time.Time) int {
+	return DefaultCalculator.GetLeapSeconds(when)
+}
+
+func getLeapSeconds(when time.Time) int {
 	adj := 10
 	year := LeapSecondsBaseYear
+	whenYear, whenMonth, _ := when.Date()
 	for {
-		if year > when.Year() {
+		if year > whenYear {
 			break
 		}
-		if year - LeapSecondsBaseYear >= len(LeapSecondsAdjustments) {
-			if when.Year() - LeapSecondsBaseYear > len(LeapSecondsAdjustments) || when.Year() - LeapSecondsBaseYear == len(LeapSecondsAdjustments) && when.Month() > time.June {
-				log.Printf("I don't know about leap seconds after %d", LeapSecondsBaseYear + len(LeapSecondsAdjustments))
-				break
-			}
-		}
 		if year - LeapSecondsBaseYear >= len(LeapSecondsAdjustments) {
 			break
 		}
 		entry := LeapSecondsAdjustments[year - LeapSecondsBaseYear]
-		if year == when.Year() {
-			if when.Month() > time.June {
+		if year == whenYear {
+			if whenMonth > time.June {
 				adj += entry[0]
 			}
 			break
@@ -102,6 +105,24 @@ func GetLeapSeconds(when time.Time) int {
 	return adj
 }
 
+/*
+returns the first instant not covered by LeapSecondsAdjustments. Leap seconds
+are only inserted at the end of June or December, so the table is good until
+the next June 30 after its last full year.
+*/
+func GetLeapSecondsExpiry() time.Time {
+	return time.Date(LeapSecondsBaseYear + len(LeapSecondsAdjustments), time.July, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Same as GetLeapSeconds, but returns ErrLeapSecondTableStale along with the last known value when the table has run out.
+func GetLeapSecondsE(when time.Time) (int, error) {
+	adj := getLeapSeconds(when)
+	if !when.Before(GetLeapSecondsExpiry()) {
+		return adj, ErrLeapSecondTableStale
+	}
+	return adj, nil
+}
+
 var DeltaT = [][]float64{
 	[]float64{43.4724, 43.5648, 43.6737, 43.7782, 43.8763, 43.9562, 44.0315, 44.1132, 44.1982, 44.2952, 44.3936}, // 1973, starting from feb
 	[]float64{44.4841, 44.5646, 44.6425, 44.7386, 44.8370, 44.9302, 44.9986, 45.0584, 45.1284, 45.2064, 45.2980, 45.3897}, // 1974
@@ -179,11 +200,7 @@ trying to adjust for pre-Gregorian dates/times seems pointless when the changeov
 happened over such wildly varying times in different regions.
 */
 func GetJulianSolarDay(when time.Time) float64 {
-	t := float64(when.UnixMicro()) / 1e6
-	t += float64(GetLeapSeconds(when))
-	t += float64(TtOffset)
-	t -= GetDeltaT(when)
-	return t / 86400.0 + GregorianDayOffset + JulianDayOffset
+	return DefaultCalculator.GetJulianSolarDay(when)
 }
 
 /*
@@ -193,10 +210,7 @@ trying to adjust for pre-Gregorian dates/times seems pointless when the changeov
 happened over such wildly varying times in different regions.
 */
 func GetJulianEphemerisDay(when time.Time) float64 {
-	t := float64(when.UnixMicro()) / 1e6
-	t += float64(GetLeapSeconds(when))
-	t += float64(TtOffset)
-	return t / 86400.0 + GregorianDayOffset + JulianDayOffset
+	return DefaultCalculator.GetJulianEphemerisDay(when)
 }
 
 func GetJulianCentury(julianDay float64) float64 {
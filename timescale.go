@@ -0,0 +1,200 @@
+package solar
+
+/*
+Conversions between the time scales used in the calculation:
+
+	UTC, civil time, as held by time.Time (which has no leap second 23:59:60)
+	TAI, international atomic time: UTC plus the leap seconds
+	TT, terrestrial time: TAI + 32.184 s, the time scale of the ephemeris
+	UT1, the rotation of the earth: TT - delta T, or UTC + DUT1
+	TDB, barycentric dynamical time: TT plus a periodic term under 2 ms
+
+The leap seconds, delta T and DUT1 come from a Calculator, so the times agree
+with those used to compute the position of the sun.
+*/
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	JdUnixEpoch = float64(2440587.5) // Julian day of 1970-01-01 00:00:00
+	J2000 = float64(2451545.0) // Julian day of 2000-01-01 12:00:00 TT
+)
+
+// TimeScale identifies an astronomical time scale.
+type TimeScale int
+
+const (
+	TimeScaleUTC TimeScale = iota
+	TimeScaleTAI
+	TimeScaleTT
+	TimeScaleUT1
+	TimeScaleTDB
+)
+
+func (scale TimeScale) String() string {
+	switch scale {
+	case TimeScaleUTC:
+		return "UTC"
+	case TimeScaleTAI:
+		return "TAI"
+	case TimeScaleTT:
+		return "TT"
+	case TimeScaleUT1:
+		return "UT1"
+	case TimeScaleTDB:
+		return "TDB"
+	}
+	return "unknown"
+}
+
+/*
+JulianDate is a Julian date split in two to keep the precision of a float64
+for the fraction: Day is the Julian date of the preceding midnight (an integer
+plus one half), and Fraction the part of the day since then, in [0, 1). A
+single float64 Julian date resolves only about 40 microseconds.
+*/
+type JulianDate struct {
+	Day float64
+	Fraction float64
+}
+
+// returns the Julian date for a day and fraction of a day, which need not be normalized
+func NewJulianDate(day, fraction float64) JulianDate {
+	midnight := math.Floor(day - 0.5) + 0.5
+	fraction += day - midnight
+	whole := math.Floor(fraction)
+	return JulianDate{midnight + whole, fraction - whole}
+}
+
+// returns the Julian date as a single number, losing precision
+func (jd JulianDate) Float() float64 {
+	return jd.Day + jd.Fraction
+}
+
+// returns the Julian date the given number of days later
+func (jd JulianDate) Add(days float64) JulianDate {
+	return NewJulianDate(jd.Day, jd.Fraction + days)
+}
+
+// returns the Julian date the given number of seconds later
+func (jd JulianDate) AddSeconds(seconds float64) JulianDate {
+	return jd.Add(seconds / 86400.0)
+}
+
+// returns the number of days from other to jd
+func (jd JulianDate) Sub(other JulianDate) float64 {
+	return (jd.Day - other.Day) + (jd.Fraction - other.Fraction)
+}
+
+/*
+returns TDB - TT in seconds at the given TT Julian date, from the leading terms
+of the series of Fairhead and Bretagnon (1990) as given in USNO Circular 179,
+eq. 2.6, which is good to about 10 microseconds from 1600 to 2200. The
+difference is periodic, mostly with a period of a year.
+*/
+func GetTDBMinusTT(jde float64) float64 {
+	t := (jde - J2000) / 36525.0
+	return 0.001657 * math.Sin(628.3076 * t + 6.2401) +
+		0.000022 * math.Sin(575.3385 * t + 4.2970) +
+		0.000014 * math.Sin(1256.6152 * t + 6.1969) +
+		0.000005 * math.Sin(606.9777 * t + 4.0212) +
+		0.000005 * math.Sin(52.9691 * t + 0.4444) +
+		0.000002 * math.Sin(21.3299 * t + 5.5431) +
+		0.000010 * t * math.Sin(628.3076 * t + 4.2490)
+}
+
+// returns the UTC Julian date of a time
+func getUTCJulianDate(when time.Time) JulianDate {
+	s := when.Unix()
+	days := s / 86400
+	if s % 86400 < 0 {
+		days -= 1
+	}
+	seconds := float64(s - days * 86400) + float64(when.Nanosecond()) / 1e9
+	return NewJulianDate(JdUnixEpoch + float64(days), seconds / 86400.0)
+}
+
+// returns the time of a UTC Julian date, rounded to the nanosecond
+func getUTCTime(jd JulianDate) time.Time {
+	days := int64(math.Round(jd.Day - JdUnixEpoch))
+	ns := math.Round(jd.Fraction * 86400e9)
+	return time.Unix(days * 86400, 0).Add(time.Duration(ns)).UTC()
+}
+
+/*
+returns the number of seconds to add to UTC to get the given time scale at the
+given time: the leap seconds for TAI, 32.184 s more for TT, TT - delta T (or
+DUT1, if the calculator has it) for UT1, and the periodic term of TDB - TT for
+TDB.
+*/
+func (c *Calculator) GetTimeScaleOffset(when time.Time, scale TimeScale) float64 {
+	switch scale {
+	case TimeScaleTAI:
+		return float64(c.GetLeapSeconds(when))
+	case TimeScaleTT:
+		return float64(c.GetLeapSeconds(when)) + TtOffset
+	case TimeScaleUT1:
+		if c.DUT1 != nil {
+			return c.DUT1.DUT1(when)
+		}
+		return float64(c.GetLeapSeconds(when)) + TtOffset - c.GetDeltaT(when)
+	case TimeScaleTDB:
+		tt := float64(c.GetLeapSeconds(when)) + TtOffset
+		return tt + GetTDBMinusTT(getUTCJulianDate(when).AddSeconds(tt).Float())
+	}
+	return 0
+}
+
+// returns the Julian date of a time in the given time scale
+func (c *Calculator) GetJulianDate(when time.Time, scale TimeScale) JulianDate {
+	return getUTCJulianDate(when).AddSeconds(c.GetTimeScaleOffset(when, scale))
+}
+
+/*
+returns the time of a Julian date in the given time scale, the inverse of
+GetJulianDate. Dates that fall in a leap second come out as the following
+second, since time.Time cannot represent 23:59:60.
+*/
+func (c *Calculator) GetTimeFromJulianDate(jd JulianDate, scale TimeScale) time.Time {
+	if scale == TimeScaleUTC {
+		return getUTCTime(jd)
+	}
+	// the offsets change slowly, so two rounds find the UTC time they apply at
+	when := getUTCTime(jd)
+	for i := 0; i < 2; i++ {
+		when = getUTCTime(jd.AddSeconds(-c.GetTimeScaleOffset(when, scale)))
+	}
+	return when
+}
+
+// returns a Julian date in one time scale as a Julian date in another
+func (c *Calculator) ConvertJulianDate(jd JulianDate, from, to TimeScale) JulianDate {
+	if from == to {
+		return jd
+	}
+	when := c.GetTimeFromJulianDate(jd, from)
+	return jd.AddSeconds(c.GetTimeScaleOffset(when, to) - c.GetTimeScaleOffset(when, from))
+}
+
+// See Calculator.GetTimeScaleOffset.
+func GetTimeScaleOffset(when time.Time, scale TimeScale) float64 {
+	return DefaultCalculator.GetTimeScaleOffset(when, scale)
+}
+
+// See Calculator.GetJulianDate.
+func GetJulianDate(when time.Time, scale TimeScale) JulianDate {
+	return DefaultCalculator.GetJulianDate(when, scale)
+}
+
+// See Calculator.GetTimeFromJulianDate.
+func GetTimeFromJulianDate(jd JulianDate, scale TimeScale) time.Time {
+	return DefaultCalculator.GetTimeFromJulianDate(jd, scale)
+}
+
+// See Calculator.ConvertJulianDate.
+func ConvertJulianDate(jd JulianDate, from, to TimeScale) JulianDate {
+	return DefaultCalculator.ConvertJulianDate(jd, from, to)
+}
@@ -0,0 +1,128 @@
+package solar
+
+/*
+Single-axis trackers turn rows of panels about one axis to follow the sun. The
+geometry follows E. Lorenzo, L. Narvarte and J. Muñoz, "Tracking and
+back-tracking," Progress in Photovoltaics 19(6), 2011, extended to sloped
+terrain by K. Anderson and M. Mikofski, "Slope-Aware Backtracking for
+Single-Axis Trackers," NREL/TP-5K00-76626, 2020.
+
+Tracker coordinates are right handed: y points along the axis toward the
+axis azimuth, x is horizontal and 90 degrees clockwise from y, and z is normal
+to both, pointing up. A positive rotation turns the panels toward +x, so for an
+axis pointing south positive rotations face west.
+*/
+
+import (
+	"math"
+)
+
+/*
+SingleAxisTracker describes a row of panels turning about one axis, and the
+rows around it.
+*/
+type SingleAxisTracker struct {
+	AxisTilt float64 // degrees from horizontal, positive when the axis dips toward AxisAzimuth
+	AxisAzimuth float64 // degrees eastward from north
+	MaxRotation float64 // degrees either side of flat
+	GroundCoverageRatio float64 // width of the panels across the axis divided by the horizontal distance between axes
+	CrossAxisTilt float64 // degrees, the slope of the terrain across the rows; see GetCrossAxisTilt
+	Backtrack bool // turn away from the sun to avoid shading the neighbouring rows
+}
+
+/*
+TrackerOrientation is the position of a single-axis tracker. All angles are in
+degrees, and azimuths are measured eastward from north.
+*/
+type TrackerOrientation struct {
+	IdealRotation float64 // rotation that faces the sun as nearly as possible
+	Rotation float64 // rotation after backtracking and the rotation limit
+	SurfaceTilt float64
+	SurfaceAzimuth float64
+	IncidenceAngle float64
+}
+
+/*
+returns the tilt of a tracker axis in degrees when it runs along terrain of
+the given slope, for a slope facing slopeAzimuth and an axis pointing toward
+axisAzimuth (both eastward from north).
+*/
+func GetTrackerAxisTilt(slopeAzimuth, slopeTilt, axisAzimuth float64) float64 {
+	return rad2deg(math.Atan(math.Cos(deg2rad(axisAzimuth - slopeAzimuth)) * math.Tan(deg2rad(slopeTilt))))
+}
+
+/*
+returns the cross-axis tilt in degrees, the slope of terrain facing
+slopeAzimuth measured across a tracker axis that lies in it, positive when the
+terrain falls toward the tracker's +x direction (for an axis pointing south,
+when it falls to the west).
+*/
+func GetCrossAxisTilt(slopeAzimuth, slopeTilt, axisAzimuth, axisTilt float64) float64 {
+	slopeAzimuthRad := deg2rad(slopeAzimuth)
+	slopeTiltRad := deg2rad(slopeTilt)
+	axisAzimuthRad := deg2rad(axisAzimuth)
+	axisTiltRad := deg2rad(axisTilt)
+	// normal of the terrain and the x and z axes of the tracker, in east, north, up coordinates
+	n := [3]float64{math.Sin(slopeTiltRad) * math.Sin(slopeAzimuthRad), math.Sin(slopeTiltRad) * math.Cos(slopeAzimuthRad), math.Cos(slopeTiltRad)}
+	x := [3]float64{math.Cos(axisAzimuthRad), -math.Sin(axisAzimuthRad), 0}
+	z := [3]float64{math.Sin(axisTiltRad) * math.Sin(axisAzimuthRad), math.Sin(axisTiltRad) * math.Cos(axisAzimuthRad), math.Cos(axisTiltRad)}
+	nx := n[0] * x[0] + n[1] * x[1] + n[2] * x[2]
+	nz := n[0] * z[0] + n[1] * z[1] + n[2] * z[2]
+	return rad2deg(math.Atan(nx / nz))
+}
+
+/*
+returns the tilt and azimuth in degrees of the surface of a tracker with the
+given axis, turned by the given rotation.
+*/
+func GetTrackerSurface(rotation, axisTilt, axisAzimuth float64) (float64, float64) {
+	rotationRad := deg2rad(rotation)
+	axisTiltRad := deg2rad(axisTilt)
+	tilt := rad2deg(math.Acos(math.Cos(rotationRad) * math.Cos(axisTiltRad)))
+	// horizontal components of the surface normal across and along the axis
+	across := math.Sin(rotationRad)
+	along := math.Cos(rotationRad) * math.Sin(axisTiltRad)
+	if across == 0 && along == 0 {
+		return tilt, limitDegrees(axisAzimuth + 90)
+	}
+	return tilt, limitDegrees(axisAzimuth + rad2deg(math.Atan2(across, along)))
+}
+
+/*
+returns the orientation of the tracker for the given position of the sun,
+using the apparent zenith. When the sun is below the horizon every angle is
+NaN, since the tracker has nothing to follow and its stow position is a matter
+of site policy.
+*/
+func (tracker SingleAxisTracker) Orientation(pos SolarPosition) TrackerOrientation {
+	if pos.Zenith > 90 {
+		nan := math.NaN()
+		return TrackerOrientation{nan, nan, nan, nan, nan}
+	}
+	zenithRad := deg2rad(pos.Zenith)
+	azimuthRad := deg2rad(pos.Azimuth)
+	sx := math.Sin(zenithRad) * math.Sin(azimuthRad)
+	sy := math.Sin(zenithRad) * math.Cos(azimuthRad)
+	sz := math.Cos(zenithRad)
+	axisAzimuthRad := deg2rad(tracker.AxisAzimuth)
+	axisTiltRad := deg2rad(tracker.AxisTilt)
+	// the sun vector in tracker coordinates; y' is not needed
+	xp := sx * math.Cos(axisAzimuthRad) - sy * math.Sin(axisAzimuthRad)
+	zp := sx * math.Sin(axisTiltRad) * math.Sin(axisAzimuthRad) + sy * math.Sin(axisTiltRad) * math.Cos(axisAzimuthRad) + sz * math.Cos(axisTiltRad)
+
+	o := TrackerOrientation{IdealRotation: rad2deg(math.Atan2(xp, zp))}
+	o.Rotation = o.IdealRotation
+	if tracker.Backtrack && tracker.GroundCoverageRatio > 0 {
+		// distance between axes along the terrain, in panel widths
+		axesDistance := 1 / (tracker.GroundCoverageRatio * math.Cos(deg2rad(tracker.CrossAxisTilt)))
+		shade := math.Abs(axesDistance * math.Cos(deg2rad(o.IdealRotation - tracker.CrossAxisTilt)))
+		// around midday the shadows do not reach the next row
+		if shade < 1 {
+			o.Rotation -= math.Copysign(rad2deg(math.Acos(shade)), o.IdealRotation)
+		}
+	}
+	o.Rotation = math.Max(-tracker.MaxRotation, math.Min(tracker.MaxRotation, o.Rotation))
+	o.SurfaceTilt, o.SurfaceAzimuth = GetTrackerSurface(o.Rotation, tracker.AxisTilt, tracker.AxisAzimuth)
+	o.IncidenceAngle = GetIncidenceAngle(pos.Zenith, o.SurfaceTilt, o.SurfaceAzimuth - 180, pos.Azimuth)
+	return o
+}
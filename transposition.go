@@ -0,0 +1,161 @@
+package solar
+
+/*
+Transposition models turn the irradiance on a horizontal surface into the
+irradiance on a tilted one, the plane of array of a solar panel. The beam
+component follows from the angle of incidence alone; the models differ in how
+they distribute the diffuse sky radiance, from the uniform sky of the
+isotropic model to the circumsolar and horizon brightening of Perez.
+*/
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	DefaultAlbedo = float64(0.25) // ground reflectance of grass and bare soil
+)
+
+// SkyDiffuseModel selects a sky diffuse transposition model for GetPlaneOfArray.
+type SkyDiffuseModel int
+
+const (
+	Isotropic SkyDiffuseModel = iota // Liu and Jordan (1963)
+	Klucher // Klucher (1979)
+	HayDavies // Hay and Davies (1980)
+	Reindl // Reindl, Beckman and Duffie (1990)
+	Perez // Perez et al. (1990), all sites composite coefficients
+)
+
+/*
+PlaneOfArray is the irradiance on a tilted surface in W/m^2, split into the
+direct beam, the diffuse light from the sky and the light reflected from the
+ground.
+*/
+type PlaneOfArray struct {
+	Beam float64
+	SkyDiffuse float64
+	GroundDiffuse float64
+}
+
+// returns the total irradiance on the surface in W/m^2
+func (poa PlaneOfArray) Global() float64 {
+	return poa.Beam + poa.SkyDiffuse + poa.GroundDiffuse
+}
+
+// returns the diffuse irradiance on the surface in W/m^2
+func (poa PlaneOfArray) Diffuse() float64 {
+	return poa.SkyDiffuse + poa.GroundDiffuse
+}
+
+// the cutoff of the Hay and Davies and Reindl models, cos(89 degrees)
+const minCosZenithTransposition = 0.01745
+
+/*
+Perez et al. (1990) all sites composite coefficients, per bin of sky
+clearness: f11, f12, f13, f21, f22, f23.
+*/
+var PerezCoefficients = [8][6]float64{
+	[6]float64{-0.008, 0.588, -0.062, -0.060, 0.072, -0.022},
+	[6]float64{0.130, 0.683, -0.151, -0.019, 0.066, -0.029},
+	[6]float64{0.330, 0.487, -0.221, 0.055, -0.064, -0.026},
+	[6]float64{0.568, 0.187, -0.295, 0.109, -0.152, -0.014},
+	[6]float64{0.873, -0.392, -0.362, 0.226, -0.462, 0.001},
+	[6]float64{1.132, -1.237, -0.412, 0.288, -0.823, 0.056},
+	[6]float64{1.060, -1.600, -0.359, 0.264, -1.127, 0.131},
+	[6]float64{0.678, -0.327, -0.250, 0.156, -1.377, 0.251},
+}
+
+/*
+returns the cosine of the angle of incidence of the sun on a surface with the
+given tilt from horizontal and azimuth in degrees, measured eastward from north
+like the azimuth of the sun.
+*/
+func GetCosIncidence(pos SolarPosition, tilt, azimuth float64) float64 {
+	return math.Cos(deg2rad(GetIncidenceAngle(pos.Zenith, tilt, azimuth - 180, pos.Azimuth)))
+}
+
+// returns the light reflected onto a tilted surface in W/m^2 by ground of the given albedo
+func GetGroundDiffuse(ghi, tilt, albedo float64) float64 {
+	return ghi * albedo * (1 - math.Cos(deg2rad(tilt))) / 2
+}
+
+/*
+returns the diffuse sky irradiance on a tilted surface in W/m^2 according to
+the given model. cosIncidence is the cosine of the angle of incidence of the
+sun on the surface; see GetCosIncidence.
+*/
+func GetSkyDiffuse(model SkyDiffuseModel, pos SolarPosition, irr Irradiance, tilt, cosIncidence float64) float64 {
+	if irr.DHI <= 0 {
+		return 0
+	}
+	tiltRad := deg2rad(tilt)
+	isotropic := (1 + math.Cos(tiltRad)) / 2
+	zenithRad := deg2rad(pos.Zenith)
+	cosZenith := math.Cos(zenithRad)
+	cosIncidence = math.Max(cosIncidence, 0)
+	switch model {
+	case Klucher:
+		f := 0.0
+		if irr.GHI > 0 {
+			f = 1 - (irr.DHI / irr.GHI) * (irr.DHI / irr.GHI)
+		}
+		sinZenith := math.Sin(zenithRad)
+		if pos.Zenith >= 90 {
+			sinZenith = 0
+		}
+		horizon := 1 + f * math.Pow(math.Sin(tiltRad / 2), 3)
+		circumsolar := 1 + f * cosIncidence * cosIncidence * sinZenith * sinZenith * sinZenith
+		return irr.DHI * isotropic * horizon * circumsolar
+	case HayDavies, Reindl:
+		// anisotropy index: the share of the diffuse light treated as circumsolar
+		ai := math.Max(irr.DNI, 0) / GetExtraterrestrialIrradiance(pos.RadiusVector)
+		rb := cosIncidence / math.Max(cosZenith, minCosZenithTransposition)
+		if model == HayDavies {
+			return irr.DHI * (ai * rb + (1 - ai) * isotropic)
+		}
+		horizon := 1.0
+		if irr.GHI > 0 {
+			horizon += math.Sqrt(math.Max(irr.DNI * cosZenith, 0) / irr.GHI) * math.Pow(math.Sin(tiltRad / 2), 3)
+		}
+		return irr.DHI * (ai * rb + (1 - ai) * isotropic * horizon)
+	case Perez:
+		if pos.Zenith >= 90 {
+			return irr.DHI * isotropic
+		}
+		const kappa = 1.041
+		z3 := kappa * zenithRad * zenithRad * zenithRad
+		clearness := ((irr.DHI + math.Max(irr.DNI, 0)) / irr.DHI + z3) / (1 + z3)
+		brightness := irr.DHI * GetAirMass(pos.Zenith, AirMassKastenYoung) / GetExtraterrestrialIrradiance(pos.RadiusVector)
+		c := PerezCoefficients[getBin(clearness, 1.065, 1.23, 1.5, 1.95, 2.8, 4.5, 6.2)]
+		f1 := math.Max(c[0] + c[1] * brightness + c[2] * zenithRad, 0)
+		f2 := c[3] + c[4] * brightness + c[5] * zenithRad
+		b := math.Max(cosZenith, math.Cos(deg2rad(85)))
+		return math.Max(irr.DHI * ((1 - f1) * isotropic + f1 * cosIncidence / b + f2 * math.Sin(tiltRad)), 0)
+	}
+	return irr.DHI * isotropic
+}
+
+/*
+returns the irradiance on a surface with the given tilt from horizontal and
+azimuth in degrees (measured eastward from north, so 180 faces south), from the
+horizontal irradiance irr, the albedo of the ground in front of the surface and
+the chosen sky diffuse model.
+*/
+func GetPlaneOfArray(pos SolarPosition, irr Irradiance, tilt, azimuth, albedo float64, model SkyDiffuseModel) PlaneOfArray {
+	cosIncidence := GetCosIncidence(pos, tilt, azimuth)
+	poa := PlaneOfArray{
+		SkyDiffuse: GetSkyDiffuse(model, pos, irr, tilt, cosIncidence),
+		GroundDiffuse: GetGroundDiffuse(irr.GHI, tilt, albedo),
+	}
+	if pos.Zenith < 90 {
+		poa.Beam = math.Max(irr.DNI * cosIncidence, 0)
+	}
+	return poa
+}
+
+// Returns the irradiance on a tilted surface at the observer; see GetPlaneOfArray.
+func (obs Observer) PlaneOfArray(when time.Time, irr Irradiance, tilt, azimuth, albedo float64, model SkyDiffuseModel) PlaneOfArray {
+	return GetPlaneOfArray(Compute(obs, when), irr, tilt, azimuth, albedo, model)
+}
@@ -0,0 +1,182 @@
+package solar
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+var (
+	ErrSunAlwaysAbove = errors.New("solar: sun stays above the requested altitude all day")
+	ErrSunAlwaysBelow = errors.New("solar: sun stays below the requested altitude all day")
+)
+
+// Direction selects the morning (Rising) or evening (Setting) crossing of an altitude.
+type Direction int
+
+const (
+	Rising Direction = iota
+	Setting
+)
+
+// Twilight is the solar depression, in degrees of altitude, that bounds a twilight period.
+type Twilight float64
+
+const (
+	CivilTwilight = Twilight(-6)
+	NauticalTwilight = Twilight(-12)
+	AstronomicalTwilight = Twilight(-18)
+)
+
+const (
+	GoldenHourHigh = float64(6) // degrees, upper edge of the golden hour
+	GoldenHourLow = float64(-4) // degrees, boundary between the golden hour and the blue hour
+	BlueHourLow = float64(-6) // degrees, lower edge of the blue hour
+)
+
+// TimeWindow is a period of time from Start to End.
+type TimeWindow struct {
+	Start time.Time
+	End time.Time
+}
+
+/*
+returns the altitude of the sun compared against a target altitude. Above the
+horizon this is the refracted altitude for the observer's atmosphere; below it
+refraction is disabled, so twilight depressions are purely geometric.
+*/
+func getCrossingAltitude(obs Observer, when time.Time, altitude float64) float64 {
+	if altitude < 0 {
+		pres := float64(0)
+		obs.Pressure = &pres
+	}
+	return obs.Altitude(when)
+}
+
+/*
+finds the instant in [t0, t1] at which f crosses zero using false position with
+the Illinois modification. f(t0) and f(t1) must have opposite signs.
+*/
+func findRoot(t0, t1 time.Time, f func(time.Time) float64) time.Time {
+	f0 := f(t0)
+	f1 := f(t1)
+	side := 0
+	for i := 0; i < 50; i++ {
+		dt := t1.Sub(t0)
+		if dt < 10 * time.Millisecond {
+			break
+		}
+		t := t0.Add(time.Duration(float64(dt) * f0 / (f0 - f1)))
+		ft := f(t)
+		if ft == 0 {
+			return t
+		}
+		if (ft > 0) == (f1 > 0) {
+			t1, f1 = t, ft
+			if side == -1 {
+				f0 /= 2
+			}
+			side = -1
+		} else {
+			t0, f0 = t, ft
+			if side == 1 {
+				f1 /= 2
+			}
+			side = 1
+		}
+	}
+	if math.Abs(f0) < math.Abs(f1) {
+		return t0
+	}
+	return t1
+}
+
+/*
+Returns the time on the given calendar date (see GetSunEvents) at which the
+sun crosses the given altitude in degrees, either rising in the morning or
+setting in the evening. The crossing searched for is the one belonging to that
+day's solar transit, so at high latitudes an evening crossing may fall shortly
+after local midnight. The result is in loc.
+
+If the sun does not reach the altitude at all that day ErrSunAlwaysBelow is
+returned, and if it never sinks to the altitude ErrSunAlwaysAbove is returned.
+*/
+func GetAltitudeCrossing(lat, lon, elevation, altitude float64, date time.Time, loc *time.Location, dir Direction) (time.Time, error) {
+	return getAltitudeCrossing(newStandardObserver(lat, lon, elevation, nil, nil), altitude, date, loc, dir)
+}
+
+func getAltitudeCrossing(obs Observer, altitude float64, date time.Time, loc *time.Location, dir Direction) (time.Time, error) {
+	if loc == nil {
+		loc = date.Location()
+	}
+	transit := obs.SunEvents(date, loc).Transit
+	var t0, t1 time.Time
+	if dir == Rising {
+		t0 = transit.Add(-12 * time.Hour)
+		t1 = transit
+	} else {
+		t0 = transit
+		t1 = transit.Add(12 * time.Hour)
+	}
+	f := func(t time.Time) float64 {
+		return getCrossingAltitude(obs, t, altitude) - altitude
+	}
+	f0 := f(t0)
+	f1 := f(t1)
+	high := math.Max(f0, f1)
+	low := math.Min(f0, f1)
+	if high < 0 {
+		return time.Time{}, ErrSunAlwaysBelow
+	}
+	if low > 0 {
+		return time.Time{}, ErrSunAlwaysAbove
+	}
+	return findRoot(t0, t1, f).In(loc), nil
+}
+
+// Returns the start of morning twilight of the given kind.
+func GetDawn(lat, lon, elevation float64, date time.Time, loc *time.Location, kind Twilight) (time.Time, error) {
+	return newStandardObserver(lat, lon, elevation, nil, nil).Dawn(date, loc, kind)
+}
+
+// Returns the end of evening twilight of the given kind.
+func GetDusk(lat, lon, elevation float64, date time.Time, loc *time.Location, kind Twilight) (time.Time, error) {
+	return newStandardObserver(lat, lon, elevation, nil, nil).Dusk(date, loc, kind)
+}
+
+// returns the morning and evening windows during which the sun is between the low and high altitudes
+func getAltitudeWindows(obs Observer, low, high float64, date time.Time, loc *time.Location) (TimeWindow, TimeWindow, error) {
+	var morning, evening TimeWindow
+	var err error
+	if morning.Start, err = getAltitudeCrossing(obs, low, date, loc, Rising); err != nil {
+		return morning, evening, err
+	}
+	if morning.End, err = getAltitudeCrossing(obs, high, date, loc, Rising); err != nil {
+		return morning, evening, err
+	}
+	if evening.Start, err = getAltitudeCrossing(obs, high, date, loc, Setting); err != nil {
+		return morning, evening, err
+	}
+	if evening.End, err = getAltitudeCrossing(obs, low, date, loc, Setting); err != nil {
+		return morning, evening, err
+	}
+	return morning, evening, nil
+}
+
+/*
+Returns the morning and evening golden hours, when the sun is between
+GoldenHourLow and GoldenHourHigh. An error is returned if the sun does not
+pass through both altitudes on that day.
+*/
+func GetGoldenHour(lat, lon, elevation float64, date time.Time, loc *time.Location) (TimeWindow, TimeWindow, error) {
+	return getAltitudeWindows(newStandardObserver(lat, lon, elevation, nil, nil), GoldenHourLow, GoldenHourHigh, date, loc)
+}
+
+/*
+Returns the morning and evening blue hours, when the sun is between
+BlueHourLow and GoldenHourLow. An error is returned if the sun does not pass
+through both altitudes on that day.
+*/
+func GetBlueHour(lat, lon, elevation float64, date time.Time, loc *time.Location) (TimeWindow, TimeWindow, error) {
+	return getAltitudeWindows(newStandardObserver(lat, lon, elevation, nil, nil), BlueHourLow, GoldenHourLow, date, loc)
+}
@@ -0,0 +1,54 @@
+package solar
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	MinValidYear = -2000 // earliest year for which the VSOP87 terms used here are valid
+	MaxValidYear = 6000 // latest year for which the VSOP87 terms used here are valid
+)
+
+var (
+	ErrTimeOutOfRange = errors.New("solar: time is outside the years -2000 to 6000 covered by the ephemeris")
+	ErrLeapSecondTableStale = errors.New("solar: time is past the end of the leap second table")
+)
+
+/*
+Checks that the ephemeris is valid at the given time. Times after the end of
+the leap second table of DefaultCalculator produce ErrLeapSecondTableStale;
+such results are still usable but may be off by the leap seconds announced
+since.
+*/
+func ValidateTime(when time.Time) error {
+	return DefaultCalculator.ValidateTime(when)
+}
+
+/*
+Same as Compute, but first validates the observer and time, returning a zero
+SolarPosition and the error if either is invalid. A time past the end of the
+leap second table is reported to the diagnostics of DefaultCalculator, and is
+an error only in Strict mode; check it with ValidateTime.
+*/
+func ComputeE(obs Observer, when time.Time) (SolarPosition, error) {
+	return DefaultCalculator.ComputeE(obs, when)
+}
+
+// Same as GetPosition, but returns an error for invalid input; see ComputeE.
+func GetPositionE(lat, lon, elevation float64, when time.Time, temperature, pressure *float64) (float64, float64, error) {
+	pos, err := ComputeE(newStandardObserver(lat, lon, elevation, temperature, pressure), when)
+	if err != nil {
+		return 0, 0, err
+	}
+	return pos.ApparentElevation, pos.Azimuth, nil
+}
+
+// Same as GetAltitude, but returns an error for invalid input; see ComputeE.
+func GetAltitudeE(lat, lon, elevation float64, when time.Time, temperature, pressure *float64) (float64, error) {
+	pos, err := ComputeE(newStandardObserver(lat, lon, elevation, temperature, pressure), when)
+	if err != nil {
+		return 0, err
+	}
+	return pos.ApparentElevation, nil
+}
@@ -1,7 +1,12 @@
 package solar
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"math"
+	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -15,7 +20,7 @@ func TestGetAltitude(t *testing.T) {
 	temp := 290.35
 	pres := float64(101862)
 	alt := GetAltitude(lat, lon, elev, when, &temp, &pres)
-	exp := float64(2.5575097320893)
+	exp := float64(2.5604960926355)
 	if math.Abs(alt - exp) > 1e-6 {
 		t.Errorf("expected %f, got %f", exp, alt)
 	}
@@ -31,3 +36,1428 @@ func TestGetRadiationDirect(t *testing.T) {
 		t.Errorf("expected %f, got %f", exp, rad)
 	}
 }
+
+func TestGetSunEvents(t *testing.T) {
+	// SPA example location and date, Reda and Andreas table A5
+	tz := time.FixedZone("MST", -7 * 3600)
+	date := time.Date(2003, time.October, 17, 0, 0, 0, 0, tz)
+	events := GetSunEvents(39.742476, -105.1786, 1830.14, date, tz)
+	if events.AlwaysUp || events.AlwaysDown {
+		t.Fatalf("unexpected polar condition: %+v", events)
+	}
+	check := func(name string, got time.Time, hour, minute, second int) {
+		exp := time.Date(2003, time.October, 17, hour, minute, second, 0, tz)
+		if math.Abs(got.Sub(exp).Seconds()) > 5 {
+			t.Errorf("%s: expected %s, got %s", name, exp, got)
+		}
+	}
+	check("sunrise", events.Sunrise, 6, 12, 43)
+	check("transit", events.Transit, 11, 46, 4)
+	if !events.Sunrise.Before(events.Transit) || !events.Transit.Before(events.Sunset) {
+		t.Errorf("events out of order: %+v", events)
+	}
+	// SPA reports sunset for the UT day, which at UTC-7 is the local sunset of the day before
+	events = GetSunEvents(39.742476, -105.1786, 1830.14, date.AddDate(0, 0, -1), tz)
+	check("sunset", events.Sunset.AddDate(0, 0, 1), 17, 20, 19)
+
+	// Tromsø, polar night and midnight sun
+	utc := time.UTC
+	events = GetSunEvents(69.6496, 18.9560, 0, time.Date(2021, time.December, 21, 0, 0, 0, 0, utc), utc)
+	if !events.AlwaysDown || !events.Sunrise.IsZero() || !events.Sunset.IsZero() {
+		t.Errorf("expected polar night, got %+v", events)
+	}
+	events = GetSunEvents(69.6496, 18.9560, 0, time.Date(2021, time.June, 21, 0, 0, 0, 0, utc), utc)
+	if !events.AlwaysUp {
+		t.Errorf("expected midnight sun, got %+v", events)
+	}
+}
+
+func TestGetAltitudeCrossing(t *testing.T) {
+	tz := time.FixedZone("MST", -7 * 3600)
+	lat, lon, elev := 39.742476, -105.1786, 1830.14
+	date := time.Date(2003, time.October, 17, 0, 0, 0, 0, tz)
+	events := GetSunEvents(lat, lon, elev, date, tz)
+	for _, kind := range []Twilight{CivilTwilight, NauticalTwilight, AstronomicalTwilight} {
+		dawn, err := GetDawn(lat, lon, elev, date, tz, kind)
+		if err != nil {
+			t.Fatalf("dawn %v: %s", kind, err)
+		}
+		dusk, err := GetDusk(lat, lon, elev, date, tz, kind)
+		if err != nil {
+			t.Fatalf("dusk %v: %s", kind, err)
+		}
+		if !dawn.Before(events.Sunrise) || !dusk.After(events.Sunset) {
+			t.Errorf("%v twilight %s - %s does not enclose the day", kind, dawn, dusk)
+		}
+		for _, when := range []time.Time{dawn, dusk} {
+			pres := float64(0)
+			alt := GetAltitude(lat, lon, elev, when, nil, &pres)
+			if math.Abs(alt - float64(kind)) > 1e-3 {
+				t.Errorf("expected altitude %f at %s, got %f", float64(kind), when, alt)
+			}
+		}
+	}
+
+	// Tromsø: the sun stays below the golden hour in December, and never gets dark in June
+	utc := time.UTC
+	if _, _, err := GetGoldenHour(69.6496, 18.9560, 0, time.Date(2021, time.December, 21, 0, 0, 0, 0, utc), utc); err != ErrSunAlwaysBelow {
+		t.Errorf("expected %s, got %v", ErrSunAlwaysBelow, err)
+	}
+	if _, err := GetDusk(69.6496, 18.9560, 0, time.Date(2021, time.June, 21, 0, 0, 0, 0, utc), utc, AstronomicalTwilight); err != ErrSunAlwaysAbove {
+		t.Errorf("expected %s, got %v", ErrSunAlwaysAbove, err)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	temp := 290.35
+	pres := float64(101862)
+	obs := Observer{Latitude: 34.2245872, Longitude: -118.0574345, Elevation: 1742, Temperature: &temp, Pressure: &pres}
+	tz, _ := time.LoadLocation("America/Los_Angeles")
+	when := time.Date(2021, time.December, 4, 16, 25, 0, 0, tz).In(time.UTC)
+	pos := Compute(obs, when)
+	alt, az := GetPosition(obs.Latitude, obs.Longitude, obs.Elevation, when, &temp, &pres)
+	if pos.ApparentElevation != alt || pos.Azimuth != az {
+		t.Errorf("expected (%f, %f), got (%f, %f)", alt, az, pos.ApparentElevation, pos.Azimuth)
+	}
+	if math.Abs(pos.Zenith + pos.ApparentElevation - 90) > 1e-9 {
+		t.Errorf("zenith %f inconsistent with elevation %f", pos.Zenith, pos.ApparentElevation)
+	}
+	// early December the equation of time is around +9.5 minutes
+	if math.Abs(pos.EquationOfTime - 9.5) > 0.5 {
+		t.Errorf("expected equation of time near 9.5 minutes, got %f", pos.EquationOfTime)
+	}
+	if pos.RadiusVector < 0.98 || pos.RadiusVector > 0.99 {
+		t.Errorf("unexpected radius vector %f", pos.RadiusVector)
+	}
+}
+
+func TestObserver(t *testing.T) {
+	obs, err := NewObserver(34.2245872, -118.0574345, 1742)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// standard atmosphere at 1742 m
+	if math.Abs(obs.temperature() - 276.827) > 1e-3 {
+		t.Errorf("expected temperature 276.827, got %f", obs.temperature())
+	}
+	if math.Abs(obs.pressure() - 82050) > 50 {
+		t.Errorf("expected pressure near 82050, got %f", obs.pressure())
+	}
+	if _, err := NewObserver(200, 0, 0); err != ErrLatitudeRange {
+		t.Errorf("expected %s, got %v", ErrLatitudeRange, err)
+	}
+	if _, err := NewObserver(0, math.NaN(), 0); err != ErrLongitudeRange {
+		t.Errorf("expected %s, got %v", ErrLongitudeRange, err)
+	}
+}
+
+func TestComputeE(t *testing.T) {
+	when := time.Date(2021, time.December, 5, 0, 25, 0, 0, time.UTC)
+	if _, err := ComputeE(Observer{Latitude: 200}, when); err != ErrLatitudeRange {
+		t.Errorf("expected %s, got %v", ErrLatitudeRange, err)
+	}
+	if _, err := ComputeE(Observer{}, time.Date(7000, time.January, 1, 0, 0, 0, 0, time.UTC)); err != ErrTimeOutOfRange {
+		t.Errorf("expected %s, got %v", ErrTimeOutOfRange, err)
+	}
+	if err := ValidateTime(GetLeapSecondsExpiry()); err != ErrLeapSecondTableStale {
+		t.Errorf("expected %s, got %v", ErrLeapSecondTableStale, err)
+	}
+	// a stale leap second table does not stop the calculation
+	if pos, err := ComputeE(Observer{}, GetLeapSecondsExpiry()); err != nil || pos.Time.IsZero() {
+		t.Errorf("expected a position despite a stale leap second table, got %v", err)
+	}
+	if _, err := ComputeE(Observer{Latitude: 34.2, Longitude: -118}, when); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+}
+
+func TestCalculatorDiagnostics(t *testing.T) {
+	warnings := []Warning{}
+	calc := &Calculator{Diagnostics: DiagnosticsFunc(func(w Warning) {
+		warnings = append(warnings, w)
+	})}
+	when := GetLeapSecondsExpiry()
+	for i := 0; i < 10; i++ {
+		if pos, err := calc.ComputeE(Observer{}, when.Add(time.Duration(i) * time.Hour)); err != nil || pos.Time.IsZero() {
+			t.Errorf("expected a position and no error, got %v", err)
+		}
+	}
+	if len(warnings) != 1 || warnings[0].Err != ErrLeapSecondTableStale {
+		t.Errorf("expected a single stale leap second warning, got %v", warnings)
+	}
+	calc.Strict = true
+	if pos, err := calc.ComputeE(Observer{}, when); err != ErrLeapSecondTableStale || !pos.Time.IsZero() {
+		t.Errorf("expected %s and no position, got %v", ErrLeapSecondTableStale, err)
+	}
+	// ValidateTime reports the condition whatever the mode
+	for _, strict := range []bool{false, true} {
+		calc.Strict = strict
+		if err := calc.ValidateTime(when); err != ErrLeapSecondTableStale || ValidateTime(when) != err {
+			t.Errorf("expected %s from ValidateTime, got %v", ErrLeapSecondTableStale, err)
+		}
+	}
+}
+
+func TestLeapSecondTables(t *testing.T) {
+	builtin := GetBuiltinLeapSecondTable()
+	list, err := LoadLeapSecondTable("testdata/leap-seconds.list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dat, err := LoadLeapSecondTable("testdata/Leap_Second.dat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list.Expires.Format("2006-01-02") != "2026-06-28" || dat.Expires.Format("2006-01-02") != "2025-12-28" {
+		t.Errorf("unexpected expiry dates %s, %s", list.Expires, dat.Expires)
+	}
+	for year := 1970; year < 2024; year++ {
+		for _, month := range []time.Month{time.March, time.September} {
+			when := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+			exp := GetLeapSeconds(when)
+			if list.Offset(when) != exp || dat.Offset(when) != exp || builtin.Offset(when) != exp {
+				t.Errorf("%s: expected %d, got %d, %d, %d", when, exp, list.Offset(when), dat.Offset(when), builtin.Offset(when))
+			}
+		}
+	}
+
+	calc := &Calculator{}
+	if err := calc.SetLeapSecondTable(list); err != nil {
+		t.Fatal(err)
+	}
+	if !calc.GetLeapSecondsExpiry().Equal(list.Expires) {
+		t.Errorf("expected expiry %s, got %s", list.Expires, calc.GetLeapSecondsExpiry())
+	}
+	// an expired table is still good for reprocessing older data, even in Strict mode
+	warnings := []Warning{}
+	calc.Diagnostics = DiagnosticsFunc(func(w Warning) {
+		warnings = append(warnings, w)
+	})
+	calc.Strict = true
+	if err := calc.SetLeapSecondTable(builtin); err != nil {
+		t.Errorf("unexpected error %s installing an expired table", err)
+	}
+	if len(warnings) != 1 || warnings[0].Err != ErrLeapSecondTableStale {
+		t.Errorf("expected a stale leap second warning, got %v", warnings)
+	}
+	if _, err := calc.ComputeE(Observer{}, time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Errorf("unexpected error %s within the table", err)
+	}
+
+	data, _ := os.ReadFile("testdata/leap-seconds.list")
+	data = bytes.Replace(data, []byte("37      # 1 Jan 2017"), []byte("38      # 1 Jan 2017"), 1)
+	if _, err := ParseLeapSecondsList(bytes.NewReader(data)); err != ErrLeapSecondHash {
+		t.Errorf("expected %s, got %v", ErrLeapSecondHash, err)
+	}
+}
+
+func TestDeltaTModels(t *testing.T) {
+	cases := []struct {
+		when time.Time
+		exp float64
+	}{
+		{time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC), -2.79},
+		{time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC), 63.86},
+		{time.Date(1820, time.January, 1, 0, 0, 0, 0, time.UTC), 12.0},
+	}
+	for _, c := range cases {
+		if dt := GetDeltaTEspenakMeeus(c.when); math.Abs(dt - c.exp) > 0.5 {
+			t.Errorf("%s: expected %f, got %f", c.when, c.exp, dt)
+		}
+	}
+	// the piecewise polynomials should join up at the boundaries
+	for _, year := range []int{500, 1600, 1700, 1800, 1860, 1900, 1920, 1941, 1961, 1986, 2005, 2050, 2150} {
+		when := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		before := GetDeltaTEspenakMeeus(when.Add(-time.Hour))
+		after := GetDeltaTEspenakMeeus(when)
+		if math.Abs(before - after) > 1.5 {
+			t.Errorf("discontinuity at %d: %f vs %f", year, before, after)
+		}
+	}
+
+	data, err := LoadDeltaTTable("testdata/deltat.data", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	when := time.Date(2013, time.March, 1, 0, 0, 0, 0, time.UTC)
+	if dt := data.DeltaT(when); dt != GetDeltaT(when) {
+		t.Errorf("expected %f, got %f", GetDeltaT(when), dt)
+	}
+	finals, err := LoadDeltaTTable("testdata/finals2000A.data", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(finals.Times) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(finals.Times))
+	}
+	when = time.Date(2017, time.January, 1, 12, 0, 0, 0, time.UTC)
+	if dt := finals.DeltaT(when); math.Abs(dt - 68.5923) > 1e-4 {
+		t.Errorf("expected 68.5923, got %f", dt)
+	}
+
+	calc := &Calculator{DeltaT: finals}
+	jd := calc.GetJulianSolarDay(when)
+	jde := calc.GetJulianEphemerisDay(when)
+	if math.Abs((jde - jd) * 86400 - finals.DeltaT(when)) > 1e-3 {
+		t.Errorf("expected JDE - JD of %f seconds, got %f", finals.DeltaT(when), (jde - jd) * 86400)
+	}
+}
+
+func TestDUT1(t *testing.T) {
+	series, err := LoadDUT1Series("testdata/finals2000A.data")
+	if err != nil {
+		t.Fatal(err)
+	}
+	when := time.Date(2017, time.January, 1, 12, 0, 0, 0, time.UTC)
+	if dut1 := series.DUT1(when); math.Abs(dut1 - 0.5916565) > 1e-7 {
+		t.Errorf("expected 0.5916565, got %f", dut1)
+	}
+	// across a leap second UT1 - UTC jumps by a second but UT1 itself is continuous
+	jump := &DUT1Series{
+		Times: []time.Time{time.Date(2016, time.December, 31, 0, 0, 0, 0, time.UTC), time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		Values: []float64{-0.4076, 0.5920},
+	}
+	if dut1 := jump.DUT1(time.Date(2016, time.December, 31, 12, 0, 0, 0, time.UTC)); math.Abs(dut1 - -0.4078) > 1e-6 {
+		t.Errorf("expected -0.4078, got %f", dut1)
+	}
+
+	calc := &Calculator{DUT1: ConstantDUT1(0.25)}
+	jd := calc.GetJulianSolarDay(when)
+	exp := float64(when.Unix()) / 86400.0 + 2440587.5 + 0.25 / 86400.0
+	if math.Abs(jd - exp) * 86400 > 1e-4 {
+		t.Errorf("expected %f, got %f", exp, jd)
+	}
+}
+
+func TestClearSky(t *testing.T) {
+	obs := Observer{Latitude: 39.742476, Longitude: -105.1786, Elevation: 1830.14}
+	noon := time.Date(2003, time.June, 17, 12, 0, 0, 0, time.FixedZone("MST", -7 * 3600))
+	pos := Compute(obs, noon)
+	cosZenith := math.Cos(deg2rad(pos.Zenith))
+
+	haurwitz := obs.ClearSkyHaurwitz(noon)
+	exp := 1098 * cosZenith * math.Exp(-0.059 / cosZenith)
+	if math.Abs(haurwitz.GHI - exp) > 1e-9 {
+		t.Errorf("expected Haurwitz GHI %f, got %f", exp, haurwitz.GHI)
+	}
+	models := map[string]Irradiance{
+		"Ineichen": obs.ClearSkyIneichen(noon, 3),
+		"Bird": obs.ClearSkyBird(noon, DefaultBirdAtmosphere),
+	}
+	for name, irr := range models {
+		if math.Abs(irr.DNI * cosZenith + irr.DHI - irr.GHI) > 1e-9 {
+			t.Errorf("%s: components %+v are inconsistent", name, irr)
+		}
+		// a clear summer noon at 1800 m
+		if irr.GHI < 950 || irr.GHI > 1100 || irr.DNI < 900 || irr.DNI > 1050 || irr.DHI < 80 || irr.DHI > 150 {
+			t.Errorf("%s: implausible clear-sky irradiance %+v", name, irr)
+		}
+	}
+	// higher turbidity gives less beam and more diffuse light
+	hazy := obs.ClearSkyIneichen(noon, 6)
+	if hazy.DNI >= models["Ineichen"].DNI || hazy.DHI <= models["Ineichen"].DHI {
+		t.Errorf("expected turbidity to shift beam to diffuse, got %+v and %+v", models["Ineichen"], hazy)
+	}
+
+	midnight := noon.Add(12 * time.Hour)
+	if irr := obs.ClearSkyBird(midnight, DefaultBirdAtmosphere); irr != (Irradiance{}) {
+		t.Errorf("expected no irradiance at night, got %+v", irr)
+	}
+	if irr := obs.ClearSkyIneichen(midnight, 3); irr != (Irradiance{}) {
+		t.Errorf("expected no irradiance at night, got %+v", irr)
+	}
+}
+
+func TestDecomposition(t *testing.T) {
+	obs := Observer{Latitude: 39.742476, Longitude: -105.1786, Elevation: 1830.14}
+	day := time.Date(2003, time.June, 17, 0, 0, 0, 0, time.FixedZone("MST", -7 * 3600))
+	ghi := []float64{}
+	positions := []SolarPosition{}
+	clear := []Irradiance{}
+	for h := 0; h < 24; h++ {
+		when := day.Add(time.Duration(h) * time.Hour)
+		irr := obs.ClearSkyIneichen(when, 3)
+		ghi = append(ghi, irr.GHI)
+		positions = append(positions, Compute(obs, when))
+		clear = append(clear, irr)
+	}
+	noon := positions[12]
+	cosZenith := math.Cos(deg2rad(noon.Zenith))
+	extra := GetExtraterrestrialIrradiance(noon.RadiusVector)
+	if kt := GetClearnessIndex(ghi[12], noon.Zenith, extra); math.Abs(kt - ghi[12] / (extra * cosZenith)) > 1e-12 {
+		t.Errorf("unexpected clearness index %f", kt)
+	}
+	models := map[string]Decomposition{
+		"Erbs": GetDecompositionErbs(ghi[12], noon),
+		"Boland": GetDecompositionBoland(ghi[12], noon),
+		"DISC": GetDecompositionDISC(ghi[12], noon, obs.pressure()),
+	}
+	brl, err := GetDecompositionBRL(ghi, positions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	models["BRL"] = brl[12]
+	for name, d := range models {
+		if math.Abs(d.DNI * cosZenith + d.DHI - d.GHI) > 1e-9 {
+			t.Errorf("%s: components %+v are inconsistent", name, d)
+		}
+		// the clear sky should decompose into mostly beam
+		if math.Abs(d.DNI - clear[12].DNI) > 0.15 * clear[12].DNI {
+			t.Errorf("%s: expected DNI near %f, got %f", name, clear[12].DNI, d.DNI)
+		}
+		if d.Extraterrestrial != extra {
+			t.Errorf("%s: expected extraterrestrial irradiance %f, got %f", name, extra, d.Extraterrestrial)
+		}
+	}
+	if d := GetDecompositionErbs(ghi[0], positions[0]); d.DNI != 0 || d.DHI != 0 {
+		t.Errorf("expected no irradiance at night, got %+v", d)
+	}
+	// overcast skies are all diffuse
+	if d := GetDecompositionErbs(0.15 * ghi[12], noon); d.DHI / d.GHI < 0.95 {
+		t.Errorf("expected a diffuse fraction near 1 under cloud, got %f", d.DHI / d.GHI)
+	}
+
+	if _, err := GetDecompositionDIRINT(ghi, positions, obs.pressure(), nil, nil); err != ErrDirintCoefficients {
+		t.Errorf("expected ErrDirintCoefficients, got %v", err)
+	}
+	if _, err := ParseDirintCoefficients(bytes.NewBufferString("1, 2, 3")); !errors.Is(err, ErrDirintFormat) {
+		t.Errorf("expected ErrDirintFormat, got %v", err)
+	}
+	// with unit coefficients DIRINT is DISC
+	ones, err := ParseDirintCoefficients(bytes.NewBufferString(strings.Repeat("1.0, ", 1259) + "1.0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirint, err := GetDecompositionDIRINT(ghi, positions, obs.pressure(), nil, ones)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range dirint {
+		disc := GetDecompositionDISC(ghi[i], positions[i], obs.pressure())
+		if math.Abs(dirint[i].DNI - disc.DNI) > 1e-9 {
+			t.Errorf("hour %d: expected DIRINT DNI %f, got %f", i, disc.DNI, dirint[i].DNI)
+		}
+	}
+
+	// a table whose entries encode their indexes shows which bins each sample falls in
+	bins := &DirintCoefficients{}
+	for i := range bins {
+		for j := range bins[i] {
+			for k := range bins[i][j] {
+				for l := range bins[i][j][k] {
+					bins[i][j][k][l] = 1 + 0.1 * float64(i) + 0.01 * float64(j) + 0.001 * float64(k) + 0.0001 * float64(l)
+				}
+			}
+		}
+	}
+	dewPoint := make([]float64, len(ghi))
+	for i := range dewPoint {
+		// 10 C, about 1.9 cm of precipitable water
+		dewPoint[i] = CelsiusOffset + 10
+	}
+	for _, water := range []int{4, 1} {
+		var dp []float64
+		if water != 4 {
+			dp = dewPoint
+		}
+		dirint, err := GetDecompositionDIRINT(ghi, positions, obs.pressure(), dp, bins)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range dirint {
+			disc := GetDecompositionDISC(ghi[i], positions[i], obs.pressure())
+			if disc.DNI == 0 {
+				continue
+			}
+			code := int(math.Round((dirint[i].DNI / disc.DNI - 1) * 10000))
+			ktBin, zenithBin, stabilityBin, waterBin := code / 1000, code / 100 % 10, code / 10 % 10, code % 10
+			ktPrime := GetZenithIndependentClearnessIndex(disc.ClearnessIndex, getDISCAirMass(positions[i].Zenith, obs.pressure()))
+			ktBounds := []float64{0.24, 0.4, 0.56, 0.7, 0.8, 1.1}
+			zenithBounds := []float64{25, 40, 55, 70, 80, 90}
+			if ktPrime >= ktBounds[ktBin] || (ktBin > 0 && ktPrime < ktBounds[ktBin - 1]) {
+				t.Errorf("hour %d: kt' %f in bin %d", i, ktPrime, ktBin)
+			}
+			if zenith := positions[i].Zenith; zenith >= zenithBounds[zenithBin] || (zenithBin > 0 && zenith < zenithBounds[zenithBin - 1]) {
+				t.Errorf("hour %d: zenith %f in bin %d", i, zenith, zenithBin)
+			}
+			// the clear sky changes smoothly, except where the sun rises and sets
+			if stabilityBin > 5 || (i > 7 && i < 17 && stabilityBin > 1) {
+				t.Errorf("hour %d: clear sky in stability bin %d", i, stabilityBin)
+			}
+			if waterBin != water {
+				t.Errorf("hour %d: expected precipitable water bin %d, got %d", i, water, waterBin)
+			}
+		}
+	}
+}
+
+func TestPlaneOfArray(t *testing.T) {
+	obs := Observer{Latitude: 39.742476, Longitude: -105.1786, Elevation: 1830.14}
+	when := time.Date(2003, time.October, 17, 12, 30, 30, 0, time.FixedZone("MST", -7 * 3600))
+	pos := Compute(obs, when)
+	irr := obs.ClearSkyIneichen(when, 3)
+	models := []SkyDiffuseModel{Isotropic, Klucher, HayDavies, Reindl, Perez}
+
+	// the SPA example surface: tilt 30, rotated 10 degrees east of south
+	if ci := GetCosIncidence(pos, 30, 170); math.Abs(ci - math.Cos(deg2rad(pos.IncidenceAngle(30, -10)))) > 1e-12 {
+		t.Errorf("unexpected cosine of incidence %f", ci)
+	}
+	for _, model := range models {
+		flat := GetPlaneOfArray(pos, irr, 0, 180, DefaultAlbedo, model)
+		if flat.GroundDiffuse != 0 {
+			t.Errorf("model %d: expected no ground reflection on a flat surface, got %f", model, flat.GroundDiffuse)
+		}
+		// Klucher brightens the circumsolar sky even on a flat surface
+		if model != Klucher && math.Abs(flat.Global() - irr.GHI) > 1e-6 {
+			t.Errorf("model %d: expected flat surface to get GHI %f, got %f", model, irr.GHI, flat.Global())
+		}
+		tilted := GetPlaneOfArray(pos, irr, 40, 180, DefaultAlbedo, model)
+		if tilted.Global() <= irr.GHI {
+			t.Errorf("model %d: expected a south facing panel in October to beat GHI %f, got %+v", model, irr.GHI, tilted)
+		}
+		if model != Isotropic && tilted.SkyDiffuse <= GetPlaneOfArray(pos, irr, 40, 180, DefaultAlbedo, Isotropic).SkyDiffuse {
+			t.Errorf("model %d: expected circumsolar brightening toward the sun, got %+v", model, tilted)
+		}
+		north := GetPlaneOfArray(pos, irr, 60, 0, DefaultAlbedo, model)
+		if north.Beam != 0 {
+			t.Errorf("model %d: expected no beam on a steep north face, got %f", model, north.Beam)
+		}
+	}
+	// under an overcast sky every model is isotropic
+	overcast := Irradiance{GHI: 200, DHI: 200}
+	for _, model := range []SkyDiffuseModel{Klucher, HayDavies, Reindl} {
+		exp := GetPlaneOfArray(pos, overcast, 35, 180, 0.2, Isotropic)
+		if got := GetPlaneOfArray(pos, overcast, 35, 180, 0.2, model); math.Abs(got.Global() - exp.Global()) > 1e-9 {
+			t.Errorf("model %d: expected %+v, got %+v", model, exp, got)
+		}
+	}
+	vertical := GetPlaneOfArray(pos, overcast, 90, 180, 0.2, Isotropic)
+	if math.Abs(vertical.SkyDiffuse - 100) > 1e-9 || math.Abs(vertical.GroundDiffuse - 20) > 1e-9 {
+		t.Errorf("expected half the sky and half the ground reflection on a wall, got %+v", vertical)
+	}
+}
+
+func TestAirMass(t *testing.T) {
+	models := []AirMassModel{AirMassKastenYoung, AirMassKasten, AirMassGueymard, AirMassYoung, AirMassPickering, AirMassPlaneParallel}
+	for _, model := range models {
+		if am := GetAirMass(0, model); math.Abs(am - 1) > 0.003 {
+			t.Errorf("model %d: expected air mass 1 overhead, got %f", model, am)
+		}
+		if am := GetAirMass(60, model); math.Abs(am - 2) > 0.01 {
+			t.Errorf("model %d: expected air mass 2 at 60 degrees, got %f", model, am)
+		}
+		if am := GetAirMass(90.5, model); !math.IsNaN(am) {
+			t.Errorf("model %d: expected NaN below the horizon, got %f", model, am)
+		}
+		if model == AirMassPlaneParallel {
+			continue
+		}
+		// about 38 at the apparent horizon, and 32 for Young's fit to the true zenith
+		if am := GetAirMass(90, model); am < 30 || am > 41 {
+			t.Errorf("model %d: expected air mass of 30 to 40 at the horizon, got %f", model, am)
+		}
+	}
+	if am := GetAbsoluteAirMass(2, StandardPressure / 2); am != 1 {
+		t.Errorf("expected absolute air mass 1, got %f", am)
+	}
+	obs := Observer{Latitude: 39.742476, Longitude: -105.1786, Elevation: 1830.14}
+	when := time.Date(2003, time.October, 17, 12, 30, 30, 0, time.FixedZone("MST", -7 * 3600))
+	exp := GetAirMass(Compute(obs, when).Zenith, AirMassKastenYoung) * GetBarometricPressure(obs.Elevation) / StandardPressure
+	if am := obs.AirMass(when, AirMassKastenYoung); math.Abs(am - exp) > 1e-12 {
+		t.Errorf("expected %f, got %f", exp, am)
+	}
+}
+
+func TestExtraterrestrialRadiation(t *testing.T) {
+	perihelion := time.Date(2003, time.January, 4, 5, 0, 0, 0, time.UTC)
+	aphelion := time.Date(2003, time.July, 4, 6, 0, 0, 0, time.UTC)
+	for _, when := range []time.Time{perihelion, aphelion, time.Date(2003, time.October, 17, 0, 0, 0, 0, time.UTC)} {
+		nrel := GetExtraterrestrialRadiation(when, SolarConstant, ExtraterrestrialNREL)
+		if spencer := GetExtraterrestrialRadiation(when, SolarConstant, ExtraterrestrialSpencer); math.Abs(spencer - nrel) > 0.002 * nrel {
+			t.Errorf("%s: Spencer %f disagrees with the ephemeris %f", when, spencer, nrel)
+		}
+		if asce := GetExtraterrestrialRadiation(when, SolarConstant, ExtraterrestrialASCE); math.Abs(asce - nrel) > 0.01 * nrel {
+			t.Errorf("%s: ASCE %f disagrees with the ephemeris %f", when, asce, nrel)
+		}
+	}
+	if e := GetExtraterrestrialRadiation(perihelion, SolarConstant, ExtraterrestrialNREL); math.Abs(e - 1412.9) > 0.5 {
+		t.Errorf("expected 1412.9 at perihelion, got %f", e)
+	}
+	if e := GetExtraterrestrialRadiation(aphelion, NominalSolarConstant, ExtraterrestrialNREL); math.Abs(e - 1316.2) > 0.5 {
+		t.Errorf("expected 1316.2 at aphelion, got %f", e)
+	}
+}
+
+func TestSingleAxisTracker(t *testing.T) {
+	check := func(name string, got, exp float64) {
+		t.Helper()
+		if math.Abs(got - exp) > 1e-4 {
+			t.Errorf("%s: expected %f, got %f", name, exp, got)
+		}
+	}
+	tracker := SingleAxisTracker{MaxRotation: 90, GroundCoverageRatio: 2.0 / 7.0, Backtrack: true}
+	noon := tracker.Orientation(SolarPosition{Zenith: 10, Azimuth: 180})
+	check("noon rotation", noon.Rotation, 0)
+	check("noon tilt", noon.SurfaceTilt, 0)
+	check("noon incidence", noon.IncidenceAngle, 10)
+
+	tracker.AxisAzimuth = 180
+	morning := tracker.Orientation(SolarPosition{Zenith: 60, Azimuth: 90})
+	check("morning rotation", morning.Rotation, -60)
+	check("morning tilt", morning.SurfaceTilt, 60)
+	check("morning azimuth", morning.SurfaceAzimuth, 90)
+	check("morning incidence", morning.IncidenceAngle, 0)
+
+	// low sun: backtracking turns the panels away to keep the rows out of each other's shade
+	tracker.AxisAzimuth = 0
+	tracker.Backtrack = false
+	low := tracker.Orientation(SolarPosition{Zenith: 80, Azimuth: 90})
+	check("ideal rotation", low.Rotation, 80)
+	check("ideal incidence", low.IncidenceAngle, 0)
+	tracker.Backtrack = true
+	low = tracker.Orientation(SolarPosition{Zenith: 80, Azimuth: 90})
+	check("ideal rotation", low.IdealRotation, 80)
+	check("backtracked rotation", low.Rotation, 27.42833)
+	check("backtracked incidence", low.IncidenceAngle, 52.5716)
+	check("backtracked azimuth", low.SurfaceAzimuth, 90)
+
+	tracker.MaxRotation = 45
+	tracker.Backtrack = false
+	check("limited rotation", tracker.Orientation(SolarPosition{Zenith: 80, Azimuth: 90}).Rotation, 45)
+	if night := tracker.Orientation(SolarPosition{Zenith: 100, Azimuth: 0}); !math.IsNaN(night.Rotation) {
+		t.Errorf("expected NaN at night, got %+v", night)
+	}
+
+	// a north-south axis on terrain falling to the west
+	check("axis tilt", GetTrackerAxisTilt(270, 10, 180), 0)
+	check("cross-axis tilt", GetCrossAxisTilt(270, 10, 180, 0), 10)
+	check("cross-axis tilt east", GetCrossAxisTilt(90, 10, 180, 0), -10)
+	check("axis tilt along slope", GetTrackerAxisTilt(180, 10, 180), 10)
+	check("cross-axis tilt along slope", GetCrossAxisTilt(180, 10, 180, 10), 0)
+	// in the afternoon the row to the west is lower, so it needs less backtracking than on flat ground
+	sloped := SingleAxisTracker{AxisAzimuth: 180, MaxRotation: 90, GroundCoverageRatio: 0.4, Backtrack: true}
+	sun := SolarPosition{Zenith: 75, Azimuth: 270}
+	flat := sloped.Orientation(sun)
+	sloped.CrossAxisTilt = GetCrossAxisTilt(270, 5, 180, 0)
+	downhill := sloped.Orientation(sun)
+	if !(downhill.Rotation > flat.Rotation && downhill.Rotation <= downhill.IdealRotation) {
+		t.Errorf("expected less backtracking on a downhill slope, got %f flat and %f sloped", flat.Rotation, downhill.Rotation)
+	}
+
+	// an axis pointing south that rises toward it, as on a slope falling to the north, faces north at rest
+	check("negative axis tilt", GetTrackerAxisTilt(0, 10, 180), -10)
+	rising := SingleAxisTracker{AxisTilt: -10, AxisAzimuth: 180, MaxRotation: 60}
+	north := rising.Orientation(SolarPosition{Zenith: 30, Azimuth: 0})
+	check("negative tilt rotation", north.Rotation, 0)
+	check("negative tilt surface tilt", north.SurfaceTilt, 10)
+	check("negative tilt surface azimuth", limitDegrees180(north.SurfaceAzimuth), 0)
+	check("negative tilt incidence", north.IncidenceAngle, 20)
+	// the incidence angle matches the rotated normal of the panels
+	for _, sun := range []SolarPosition{{Zenith: 50, Azimuth: 100}, {Zenith: 20, Azimuth: 200}, {Zenith: 70, Azimuth: 290}} {
+		o := rising.Orientation(sun)
+		rotationRad := deg2rad(o.Rotation)
+		axis := GetDirection(0, rising.AxisAzimuth)
+		across := GetDirection(0, rising.AxisAzimuth + 90)
+		up := ENU{Up: 1}
+		z := axis.Scale(math.Sin(deg2rad(rising.AxisTilt))).Add(up.Scale(math.Cos(deg2rad(rising.AxisTilt))))
+		normal := z.Scale(math.Cos(rotationRad)).Add(across.Scale(math.Sin(rotationRad)))
+		check("negative tilt incidence from the normal", o.IncidenceAngle, rad2deg(math.Acos(normal.Dot(GetDirection(90 - sun.Zenith, sun.Azimuth)))))
+		elevation, azimuth := normal.Angles()
+		check("negative tilt surface tilt from the normal", o.SurfaceTilt, 90 - elevation)
+		check("negative tilt surface azimuth from the normal", o.SurfaceAzimuth, azimuth)
+	}
+}
+
+func TestHeliostat(t *testing.T) {
+	obs := Observer{Latitude: 39.742476, Longitude: -105.1786, Elevation: 1830.14}
+	when := time.Date(2003, time.October, 17, 12, 30, 30, 0, time.FixedZone("MST", -7 * 3600))
+	pos := Compute(obs, when)
+	azel := DualAxisMount{Type: AzimuthElevation}
+	tracking := azel.Track(pos)
+	if math.Abs(tracking.Primary - pos.Azimuth) > 1e-9 || math.Abs(tracking.Secondary - pos.ApparentElevation) > 1e-9 || math.Abs(tracking.CosineEfficiency - 1) > 1e-12 {
+		t.Errorf("unexpected tracking aim %+v", tracking)
+	}
+
+	mounts := []DualAxisMount{azel, DualAxisMount{Type: TiltRoll}, DualAxisMount{Type: TiltRoll, Azimuth: 30}}
+	for _, m := range mounts {
+		for _, d := range []ENU{ENU{0.3, -0.2, 0.9}, ENU{-0.5, 0.5, 0.2}, ENU{0, 0, 1}} {
+			primary, secondary := m.Angles(d)
+			if got := m.Direction(primary, secondary); got.Sub(d.Unit()).Norm() > 1e-12 {
+				t.Errorf("mount %+v: expected direction %+v, got %+v", m, d.Unit(), got)
+			}
+		}
+	}
+
+	// a tower 100 m north of the mirror and 50 m up
+	mirror := ENU{0, 0, 2}
+	target := ENU{0, 100, 52}
+	for _, m := range mounts {
+		aim := m.Heliostat(pos, mirror, target)
+		reflected := GetReflection(pos.SunVector().Scale(-1), aim.Normal)
+		if reflected.Sub(target.Sub(mirror).Unit()).Norm() > 1e-12 {
+			t.Errorf("mount %+v: reflection %+v misses the target", m, reflected)
+		}
+		if got := m.Direction(aim.Primary, aim.Secondary); got.Sub(aim.Normal).Norm() > 1e-12 {
+			t.Errorf("mount %+v: angles %f, %f do not reproduce the normal", m, aim.Primary, aim.Secondary)
+		}
+	}
+	aim := azel.Heliostat(pos, mirror, target)
+	exp := math.Sqrt((1 + pos.SunVector().Dot(target.Sub(mirror).Unit())) / 2)
+	if math.Abs(aim.CosineEfficiency - exp) > 1e-12 {
+		t.Errorf("expected cosine efficiency %f, got %f", exp, aim.CosineEfficiency)
+	}
+	// with the sun in the south, a mirror north of the tower does far better than one south of it
+	north := azel.Heliostat(pos, ENU{0, 200, 2}, target)
+	if north.CosineEfficiency < 0.95 || aim.CosineEfficiency > 0.6 {
+		t.Errorf("expected the north field to beat the south field, got %f and %f", north.CosineEfficiency, aim.CosineEfficiency)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	obs := Observer{Latitude: 39.742476, Longitude: -105.1786, Elevation: 1830.14}
+	start := time.Date(2003, time.October, 17, 0, 0, 0, 0, time.UTC)
+	end := start.Add(24 * time.Hour)
+	n := GetRangeLength(start, end, 7 * time.Minute)
+	if n != 206 {
+		t.Fatalf("expected 206 samples, got %d", n)
+	}
+	out := make([]SolarPosition, n)
+	if _, err := ComputeRange(obs, start, end, 7 * time.Minute, out[:10]); err != ErrBatchLength {
+		t.Errorf("expected ErrBatchLength, got %v", err)
+	}
+	if _, err := ComputeRange(obs, start, end, 0, out); err != ErrBatchStep {
+		t.Errorf("expected ErrBatchStep, got %v", err)
+	}
+	count, err := ComputeRange(obs, start, end, 7 * time.Minute, out)
+	if err != nil || count != n {
+		t.Fatalf("expected %d positions, got %d, %v", n, count, err)
+	}
+	times := make([]time.Time, n)
+	for i := range times {
+		times[i] = start.Add(time.Duration(i) * 7 * time.Minute)
+	}
+	batch := make([]SolarPosition, n)
+	if err := ComputeBatch(obs, times, batch); err != nil {
+		t.Fatal(err)
+	}
+	altitude := make([]float64, n)
+	azimuth := make([]float64, n)
+	if err := GetPositions(obs, times, altitude, azimuth); err != nil {
+		t.Fatal(err)
+	}
+	for i, when := range times {
+		exp := Compute(obs, when)
+		if out[i] != exp || batch[i] != exp {
+			t.Errorf("%s: batch result differs from Compute", when)
+		}
+		if altitude[i] != exp.ApparentElevation || azimuth[i] != exp.Azimuth {
+			t.Errorf("%s: expected %f, %f, got %f, %f", when, exp.ApparentElevation, exp.Azimuth, altitude[i], azimuth[i])
+		}
+	}
+
+	// a stale leap second table fails a batch only in Strict mode
+	expiry := GetLeapSecondsExpiry()
+	stale := []time.Time{expiry.Add(-time.Hour), expiry}
+	calc := &Calculator{}
+	if err := calc.ComputeBatch(obs, stale, batch); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	calc.Strict = true
+	if err := calc.ComputeBatch(obs, stale, batch); err != ErrLeapSecondTableStale {
+		t.Errorf("expected %s, got %v", ErrLeapSecondTableStale, err)
+	}
+	if err := calc.ComputeBatch(obs, stale[:1], batch); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	if _, err := calc.ComputeRange(obs, stale[0], expiry.Add(time.Minute), time.Minute, out); err != ErrLeapSecondTableStale {
+		t.Errorf("expected %s from the range, got %v", ErrLeapSecondTableStale, err)
+	}
+	if _, err := calc.ComputeRange(obs, stale[0], expiry, time.Minute, out); err != nil {
+		t.Errorf("unexpected error %s from the range", err)
+	}
+	if err := calc.GetPositions(obs, stale, altitude, azimuth); err != ErrLeapSecondTableStale {
+		t.Errorf("expected %s from the positions, got %v", ErrLeapSecondTableStale, err)
+	}
+}
+
+func benchmarkTimes() (Observer, []time.Time) {
+	obs := Observer{Latitude: 39.742476, Longitude: -105.1786, Elevation: 1830.14}
+	start := time.Date(2021, time.June, 1, 0, 0, 0, 0, time.UTC)
+	times := make([]time.Time, 1440)
+	for i := range times {
+		times[i] = start.Add(time.Duration(i) * time.Minute)
+	}
+	return obs, times
+}
+
+func BenchmarkGetPosition(b *testing.B) {
+	obs, times := benchmarkTimes()
+	temp := obs.temperature()
+	pres := obs.pressure()
+	b.ReportAllocs()
+	b.ResetTimer()
+	began := time.Now()
+	for i := 0; i < b.N; i++ {
+		for _, when := range times {
+			GetPosition(obs.Latitude, obs.Longitude, obs.Elevation, when, &temp, &pres)
+		}
+	}
+	b.ReportMetric(float64(b.N * len(times)) / time.Since(began).Seconds(), "positions/s")
+}
+
+func BenchmarkGetPositions(b *testing.B) {
+	obs, times := benchmarkTimes()
+	altitude := make([]float64, len(times))
+	azimuth := make([]float64, len(times))
+	b.ResetTimer()
+	began := time.Now()
+	for i := 0; i < b.N; i++ {
+		GetPositions(obs, times, altitude, azimuth)
+	}
+	b.ReportMetric(float64(b.N * len(times)) / time.Since(began).Seconds(), "positions/s")
+}
+
+func BenchmarkComputeRange(b *testing.B) {
+	obs, times := benchmarkTimes()
+	out := make([]SolarPosition, len(times))
+	start := times[0]
+	end := start.Add(24 * time.Hour)
+	b.ReportAllocs()
+	b.ResetTimer()
+	began := time.Now()
+	for i := 0; i < b.N; i++ {
+		ComputeRange(obs, start, end, time.Minute, out)
+	}
+	b.ReportMetric(float64(b.N * len(times)) / time.Since(began).Seconds(), "positions/s")
+}
+
+func TestBatchEngine(t *testing.T) {
+	start := time.Date(2021, time.June, 1, 0, 0, 0, 0, time.UTC)
+	jobs := []BatchJob{}
+	for i := 0; i < 12; i++ {
+		obs := Observer{Latitude: float64(i * 15 - 80), Longitude: float64(i * 30 - 180), Elevation: 100}
+		jobs = append(jobs, BatchJob{Observer: obs, Start: start, End: start.Add(time.Duration(i * 7) * time.Hour), Step: 10 * time.Minute})
+	}
+	jobs = append(jobs, BatchJob{Observer: jobs[3].Observer, Times: []time.Time{start.Add(time.Hour), start}})
+	engine := &BatchEngine{Workers: 4, ChunkSize: 17}
+	next := 0
+	offset := 0
+	err := engine.Run(context.Background(), jobs, func(result BatchResult) error {
+		if result.Job != next || result.Offset != offset {
+			t.Fatalf("expected job %d offset %d, got job %d offset %d", next, offset, result.Job, result.Offset)
+		}
+		job := jobs[result.Job]
+		for i, pos := range result.Positions {
+			if exp := Compute(job.Observer, job.time(result.Offset + i)); pos != exp {
+				t.Errorf("job %d, position %d differs from Compute", result.Job, result.Offset + i)
+			}
+		}
+		offset += len(result.Positions)
+		if result.Done {
+			if offset != job.length() {
+				t.Errorf("job %d: expected %d positions, got %d", result.Job, job.length(), offset)
+			}
+			next += 1
+			offset = 0
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != len(jobs) {
+		t.Errorf("expected %d jobs, got %d", len(jobs), next)
+	}
+
+	// cancellation stops the run promptly
+	ctx, cancel := context.WithCancel(context.Background())
+	count := 0
+	err = engine.Run(ctx, jobs, func(result BatchResult) error {
+		count += 1
+		if count == 3 {
+			cancel()
+		}
+		return nil
+	})
+	if err != context.Canceled || count != 3 {
+		t.Errorf("expected cancellation after 3 chunks, got %v after %d", err, count)
+	}
+	year := []BatchJob{BatchJob{Observer: jobs[0].Observer, Start: start, End: start.AddDate(1, 0, 0), Step: time.Second}}
+	ctx, cancel = context.WithTimeout(context.Background(), 20 * time.Millisecond)
+	defer cancel()
+	if err := engine.Run(ctx, year, func(BatchResult) error { return nil }); err != context.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+	stop := errors.New("stop")
+	if err := engine.Run(context.Background(), jobs, func(BatchResult) error { return stop }); err != stop {
+		t.Errorf("expected the callback's error, got %v", err)
+	}
+
+	total := 0
+	for result := range engine.Stream(context.Background(), jobs[:4]) {
+		total += len(result.Positions)
+	}
+	if exp := jobs[1].length() + jobs[2].length() + jobs[3].length(); total != exp {
+		t.Errorf("expected %d streamed positions, got %d", exp, total)
+	}
+
+	// in Strict mode a job past the end of the leap second table fails the run, and the stream says so
+	expiry := GetLeapSecondsExpiry()
+	stale := []BatchJob{jobs[1], BatchJob{Observer: jobs[0].Observer, Start: expiry.Add(-time.Hour), End: expiry.Add(time.Hour), Step: time.Minute}}
+	strict := &BatchEngine{Calculator: &Calculator{Strict: true}, Workers: 2}
+	if err := strict.Run(context.Background(), stale, func(BatchResult) error { return nil }); err != ErrLeapSecondTableStale {
+		t.Errorf("expected %s, got %v", ErrLeapSecondTableStale, err)
+	}
+	results := []BatchResult{}
+	for result := range strict.Stream(context.Background(), stale) {
+		results = append(results, result)
+	}
+	if len(results) != 1 || results[0].Err != ErrLeapSecondTableStale || len(results[0].Positions) != 0 {
+		t.Errorf("expected a single result with %s, got %+v", ErrLeapSecondTableStale, results)
+	}
+	if err := strict.Run(context.Background(), stale[:1], func(BatchResult) error { return nil }); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	lenient := &BatchEngine{Calculator: &Calculator{}, Workers: 2}
+	for result := range lenient.Stream(context.Background(), stale) {
+		if result.Err != nil {
+			t.Errorf("unexpected error %s outside Strict mode", result.Err)
+		}
+	}
+}
+
+func BenchmarkBatchEngine(b *testing.B) {
+	obs, times := benchmarkTimes()
+	jobs := make([]BatchJob, 16)
+	for i := range jobs {
+		jobs[i] = BatchJob{Observer: obs, Times: times}
+	}
+	engine := &BatchEngine{}
+	b.ResetTimer()
+	began := time.Now()
+	for i := 0; i < b.N; i++ {
+		engine.Run(context.Background(), jobs, func(BatchResult) error { return nil })
+	}
+	b.ReportMetric(float64(b.N * len(jobs) * len(times)) / time.Since(began).Seconds(), "positions/s")
+}
+
+func TestNutationAngles(t *testing.T) {
+	jd := GetJulianSolarDay(time.Date(2003, time.October, 17, 19, 30, 30, 0, time.UTC))
+	jce := GetJulianEphemerisCentury(jd + 67.0 / 86400.0)
+	n := GetNutationAngles(jce)
+	m := GetNutation(jce)
+	if n.Longitude != m["longitude"] || n.Obliquity != m["obliquity"] {
+		t.Errorf("GetNutation %v differs from GetNutationAngles %v", m, n)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		n = GetNutationAngles(jce)
+		GetApparentSiderealTime(jd, jce / 10, n)
+		GetApparentSunLongitude(204.0085537528, n, -0.005711)
+	})
+	if allocs != 0 {
+		t.Errorf("nutation made %v allocations, expected none", allocs)
+	}
+	obs, times := benchmarkTimes()
+	allocs = testing.AllocsPerRun(100, func() {
+		Compute(obs, times[720])
+	})
+	if allocs != 0 {
+		t.Errorf("Compute made %v allocations, expected none", allocs)
+	}
+}
+
+func BenchmarkGetNutationAngles(b *testing.B) {
+	jce := GetJulianEphemerisCentury(GetJulianEphemerisDay(time.Date(2021, time.June, 1, 0, 0, 0, 0, time.UTC)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		GetNutationAngles(jce)
+	}
+}
+
+func BenchmarkGetApparentSiderealTime(b *testing.B) {
+	jd := GetJulianEphemerisDay(time.Date(2021, time.June, 1, 0, 0, 0, 0, time.UTC))
+	jce := GetJulianEphemerisCentury(jd)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		GetApparentSiderealTime(jd, jce / 10, GetNutationAngles(jce))
+	}
+}
+
+func TestEphemerisCache(t *testing.T) {
+	check := func(cache *EphemerisCache, tolerance, eotTolerance float64) {
+		start := time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC)
+		for i := 0; i < 2000; i++ {
+			when := start.Add(time.Duration(i) * 157 * time.Hour + time.Duration(i * i) * time.Second)
+			obs := Observer{Latitude: float64(i % 179) - 89, Longitude: float64(i * 37 % 360) - 180, Elevation: float64(i % 3000)}
+			expected := Compute(obs, when)
+			pos := cache.Compute(obs, when)
+			errs := map[string]float64{
+				"right ascension": limitDegrees180(pos.RightAscension - expected.RightAscension),
+				"declination": pos.Declination - expected.Declination,
+				"apparent sun longitude": limitDegrees180(pos.ApparentSunLongitude - expected.ApparentSunLongitude),
+				"zenith": pos.Zenith - expected.Zenith,
+				"direction": rad2deg(pos.SunVector().Sub(expected.SunVector()).Norm()),
+			}
+			for k, err := range errs {
+				if math.Abs(err) > tolerance {
+					t.Fatalf("%v at %s: %s off by %g", obs, when, k, err)
+				}
+			}
+			if err := pos.EquationOfTime - expected.EquationOfTime; math.Abs(err) > eotTolerance {
+				t.Fatalf("at %s: equation of time off by %g", when, err)
+			}
+		}
+	}
+	check(NewEphemerisCache(nil, MaxEphemerisInterval), EphemerisTolerance, 1e-4)
+	check(SharedEphemerisCache, 1e-8, 1e-7)
+
+	// a shared cache used from several goroutines agrees with the full calculation
+	cache := NewEphemerisCache(nil, 10 * time.Minute)
+	obs, times := benchmarkTimes()
+	done := make(chan error)
+	for g := 0; g < 8; g++ {
+		go func(g int) {
+			for i := g; i < len(times); i += 8 {
+				alt, az := cache.Position(obs, times[i])
+				ealt, eaz := obs.Position(times[i])
+				if math.Abs(alt - ealt) > 1e-8 || math.Abs(az - eaz) > 1e-8 {
+					done <- errors.New(times[i].String())
+					return
+				}
+			}
+			done <- nil
+		}(g)
+	}
+	for g := 0; g < 8; g++ {
+		if err := <-done; err != nil {
+			t.Errorf("cached position at %s differs", err)
+		}
+	}
+
+	// a full cache evicts the node furthest from the one requested, keeping its neighbours
+	cache = NewEphemerisCache(nil, time.Hour)
+	base := int64(2459000 * 24)
+	for k := base; k < base + ephemerisCacheSize; k++ {
+		cache.node(k)
+	}
+	kept := cache.node(base + ephemerisCacheSize - 1)
+	cache.node(base + ephemerisCacheSize)
+	cache.node(base - 2)
+	if len(cache.nodes) != ephemerisCacheSize {
+		t.Errorf("expected %d cached nodes, got %d", ephemerisCacheSize, len(cache.nodes))
+	}
+	if _, ok := cache.nodes[base + 1]; !ok {
+		t.Errorf("node near the requested one was evicted")
+	}
+	for _, k := range []int64{base, base + ephemerisCacheSize} {
+		if _, ok := cache.nodes[k]; ok {
+			t.Errorf("furthest node %d was not evicted", k - base)
+		}
+	}
+	if cache.nodes[base + ephemerisCacheSize - 1] != kept {
+		t.Errorf("node was recomputed instead of kept")
+	}
+}
+
+func BenchmarkEphemerisCache(b *testing.B) {
+	obs, times := benchmarkTimes()
+	// 10 Hz for a little over two minutes
+	for i := range times {
+		times[i] = times[0].Add(time.Duration(i) * 100 * time.Millisecond)
+	}
+	cache := NewEphemerisCache(nil, 0)
+	b.ReportAllocs()
+	b.ResetTimer()
+	began := time.Now()
+	for i := 0; i < b.N; i++ {
+		for _, when := range times {
+			cache.Compute(obs, when)
+		}
+	}
+	b.ReportMetric(float64(b.N * len(times)) / time.Since(began).Seconds(), "positions/s")
+}
+
+func TestTimeScales(t *testing.T) {
+	calc := &Calculator{}
+	when := time.Date(2000, time.January, 1, 12, 0, 0, 0, time.UTC)
+	jd := calc.GetJulianDate(when, TimeScaleUTC)
+	if jd.Day != 2451544.5 || jd.Fraction != 0.5 {
+		t.Errorf("UTC Julian date of %s is %v, expected 2451544.5 + 0.5", when, jd)
+	}
+	when = time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+	offsets := map[TimeScale]float64{
+		TimeScaleUTC: 0,
+		TimeScaleTAI: 37,
+		TimeScaleTT: 69.184,
+		TimeScaleUT1: 69.184 - calc.GetDeltaT(when),
+	}
+	for scale, expected := range offsets {
+		if offset := calc.GetTimeScaleOffset(when, scale); math.Abs(offset - expected) > 1e-9 {
+			t.Errorf("%s - UTC at %s is %g, expected %g", scale, when, offset, expected)
+		}
+	}
+	dut1 := &Calculator{DUT1: ConstantDUT1(0.4)}
+	if offset := dut1.GetTimeScaleOffset(when, TimeScaleUT1); offset != 0.4 {
+		t.Errorf("UT1 - UTC with DUT1 0.4 is %g", offset)
+	}
+
+	// TDB - TT peaks at about 1.7 ms, early in April and October
+	peak := 0.0
+	for d := 0; d < 366; d++ {
+		tdb := calc.GetTimeScaleOffset(when.AddDate(0, 0, d), TimeScaleTDB) - calc.GetTimeScaleOffset(when.AddDate(0, 0, d), TimeScaleTT)
+		if math.Abs(tdb) > math.Abs(peak) {
+			peak = tdb
+		}
+	}
+	if math.Abs(peak) < 0.0016 || math.Abs(peak) > 0.0018 {
+		t.Errorf("TDB - TT peaks at %g s, expected about 0.0017", peak)
+	}
+
+	// round trips, to the nanosecond
+	scales := []TimeScale{TimeScaleUTC, TimeScaleTAI, TimeScaleTT, TimeScaleUT1, TimeScaleTDB}
+	start := time.Date(1975, time.March, 3, 4, 5, 6, 789, time.UTC)
+	for i := 0; i < 500; i++ {
+		when := start.Add(time.Duration(i) * 1753 * time.Hour + time.Duration(i * 7919))
+		for _, scale := range scales {
+			jd := calc.GetJulianDate(when, scale)
+			if jd.Fraction < 0 || jd.Fraction >= 1 || jd.Day - math.Floor(jd.Day) != 0.5 {
+				t.Fatalf("%s Julian date of %s is not normalized: %v", scale, when, jd)
+			}
+			if back := calc.GetTimeFromJulianDate(jd, scale); !back.Equal(when) {
+				t.Errorf("%s Julian date of %s comes back as %s", scale, when, back)
+			}
+			for _, to := range scales {
+				converted := calc.ConvertJulianDate(jd, scale, to)
+				if d := converted.Sub(calc.GetJulianDate(when, to)) * 86400; math.Abs(d) > 1e-9 {
+					t.Errorf("%s to %s at %s is off by %g s", scale, to, when, d)
+				}
+				if d := calc.ConvertJulianDate(converted, to, scale).Sub(jd) * 86400; math.Abs(d) > 1e-9 {
+					t.Errorf("%s to %s and back at %s is off by %g s", scale, to, when, d)
+				}
+			}
+		}
+	}
+
+	// a nanosecond is still visible in a two-part date
+	a := calc.GetJulianDate(start, TimeScaleTT)
+	b := calc.GetJulianDate(start.Add(time.Nanosecond), TimeScaleTT)
+	if d := b.Sub(a) * 86400e9; math.Abs(d - 1) > 1e-3 {
+		t.Errorf("Julian dates a nanosecond apart differ by %g ns", d)
+	}
+	if jd := NewJulianDate(2451545.0, -0.75); jd.Day != 2451543.5 || jd.Fraction != 0.75 {
+		t.Errorf("NewJulianDate(2451545.0, -0.75) = %v, expected 2451543.5 + 0.75", jd)
+	}
+}
+
+func TestSolarTime(t *testing.T) {
+	ny, _ := time.LoadLocation("America/New_York")
+	when := time.Date(2021, time.November, 3, 15, 30, 0, 0, time.UTC)
+	// with UT1 = UTC, local mean time is UTC shifted by the longitude
+	calc := &Calculator{DUT1: ConstantDUT1(0)}
+	if lmt := calc.GetLocalMeanTime(0, when.In(ny)); lmt != 15 * time.Hour + 30 * time.Minute {
+		t.Errorf("local mean time at Greenwich at %s is %s", when, lmt)
+	}
+	if lmt := calc.GetLocalMeanTime(-75, when); lmt != 10 * time.Hour + 30 * time.Minute {
+		t.Errorf("local mean time at 75W at %s is %s", when, lmt)
+	}
+	if lmt := calc.GetLocalMeanTime(150, when); lmt != 90 * time.Minute {
+		t.Errorf("local mean time at 150E at %s is %s", when, lmt)
+	}
+	calc.DUT1 = ConstantDUT1(-0.25)
+	if lmt := calc.GetLocalMeanTime(0, when); lmt != 15 * time.Hour + 30 * time.Minute - 250 * time.Millisecond {
+		t.Errorf("local mean time at Greenwich with DUT1 -0.25 at %s is %s", when, lmt)
+	}
+	// early November the equation of time is at its largest, about +16.4 minutes
+	eot := GetLocalApparentTime(-75, when.In(ny)) - GetLocalMeanTime(-75, when)
+	if eot < 16 * time.Minute + 20 * time.Second || eot > 16 * time.Minute + 30 * time.Second {
+		t.Errorf("equation of time at %s is %s", when, eot)
+	}
+	if hours := GetSolarTime(-75, when.In(ny)); math.Abs(hours - 10.77) > 0.02 {
+		t.Errorf("GetSolarTime(-75, %s) = %g hours, expected about 10.77", when, hours)
+	}
+
+	// the sun transits at local apparent noon
+	for _, lon := range []float64{-105.1786, 0, 139.69} {
+		for m := time.January; m <= time.December; m++ {
+			date := time.Date(2021, m, 14, 0, 0, 0, 0, time.UTC)
+			transit := GetSunEvents(40, lon, 0, date, time.UTC).Transit
+			if d := GetLocalApparentTime(lon, transit) - 12 * time.Hour; d < -time.Second || d > time.Second {
+				t.Errorf("local apparent time at transit at %g on %s is off by %s", lon, date.Format("2006-01-02"), d)
+			}
+			noon := GetTimeFromLocalApparentTime(lon, date, 12 * time.Hour)
+			if d := noon.Sub(transit); d < -time.Second || d > time.Second {
+				t.Errorf("apparent noon at %g on %s is %s, transit is %s", lon, date.Format("2006-01-02"), noon, transit)
+			}
+		}
+	}
+
+	// round trips, keeping the location of the date
+	date := time.Date(2021, time.February, 11, 0, 0, 0, 0, ny)
+	for _, lon := range []float64{-170, -74, 0, 45.5, 179} {
+		for h := 0; h < 24; h += 5 {
+			target := time.Duration(h) * time.Hour + 17 * time.Minute + 3 * time.Second
+			when := GetTimeFromLocalApparentTime(lon, date, target)
+			if when.Location() != ny {
+				t.Errorf("GetTimeFromLocalApparentTime returned a time in %s", when.Location())
+			}
+			if d := GetLocalApparentTime(lon, when) - target; d < -time.Millisecond || d > time.Millisecond {
+				t.Errorf("local apparent time %s at %g comes back off by %s", target, lon, d)
+			}
+			if lmt := GetLocalMeanTime(lon, GetTimeFromLocalMeanTime(lon, date, target)); lmt != target {
+				t.Errorf("local mean time %s at %g comes back as %s", target, lon, lmt)
+			}
+		}
+	}
+}
+
+func TestSeasonalEvents(t *testing.T) {
+	// USNO Astronomical Applications, rounded to the minute
+	almanac := map[int][4]string{
+		2020: {"2020-03-20 03:50", "2020-06-20 21:44", "2020-09-22 13:31", "2020-12-21 10:02"},
+		2021: {"2021-03-20 09:37", "2021-06-21 03:32", "2021-09-22 19:21", "2021-12-21 15:59"},
+		2024: {"2024-03-20 03:06", "2024-06-20 20:51", "2024-09-22 12:44", "2024-12-21 09:20"},
+	}
+	calc := &Calculator{DeltaT: EspenakMeeusDeltaT}
+	for year, dates := range almanac {
+		for i, date := range dates {
+			event := SeasonalEvent(2 * i + 1)
+			expected, _ := time.Parse("2006-01-02 15:04", date)
+			when := calc.GetSeasonalEvent(year, event)
+			if d := when.Sub(expected); d < -31 * time.Second || d > 31 * time.Second {
+				t.Errorf("%d %s at %s, expected %s", year, event, when, expected)
+			}
+		}
+	}
+
+	// Meeus, example 27.a: the June solstice of 1962 from the full VSOP87 theory, 21:24:42 TT
+	jde := calc.GetJulianDate(calc.GetSeasonalEvent(1962, JuneSolstice), TimeScaleTT)
+	if d := jde.Sub(NewJulianDate(2437836.5, (21 * 3600 + 24 * 60 + 42) / 86400.0)) * 86400; math.Abs(d) > 5 {
+		t.Errorf("1962 June solstice is %g s from Meeus", d)
+	}
+
+	for _, year := range []int{1901, 1999, 2000, 2023, 2099} {
+		events := calc.GetSeasonalEvents(year)
+		for i, when := range events {
+			event := SeasonalEvent(i)
+			if when.Year() != year || (i > 0 && !when.After(events[i - 1])) {
+				t.Errorf("%d %s at %s is out of order", year, event, when)
+			}
+			// the sun moves about 1.2e-5 degrees a second
+			if d := limitDegrees180(calc.getApparentSunLongitude(when) - event.Longitude()); math.Abs(d) > 1e-6 {
+				t.Errorf("sun longitude at %d %s is off by %g", year, event, d)
+			}
+		}
+	}
+	if when := calc.GetSunLongitudeTime(2021, 280.1); when.Year() != 2021 {
+		t.Errorf("sun longitude 280.1 found at %s, expected in 2021", when)
+	}
+}
+
+func TestMoon(t *testing.T) {
+	// Meeus, examples 47.a and 48.a: 1992 April 12 at 0h TD
+	jce := GetJulianEphemerisCentury(2448724.5)
+	args := getMoonArguments(jce)
+	checkValue(t, "L'", args.meanLongitude, 134.290182, 1e-6)
+	checkValue(t, "D", args.meanElongation, 113.842304, 1e-6)
+	checkValue(t, "M", args.sunMeanAnomaly, 97.643514, 1e-6)
+	checkValue(t, "M'", args.meanAnomaly, 5.150833, 1e-6)
+	checkValue(t, "F", args.argumentOfLatitude, 219.889721, 1e-6)
+	longitude, latitude, distance := GetMoonGeocentric(jce)
+	checkValue(t, "λ", longitude, 133.162655, 1e-6)
+	checkValue(t, "β", latitude, -3.229126, 1e-6)
+	checkValue(t, "Δ", distance, 368409.7, 0.1)
+	checkValue(t, "π", GetMoonParallax(distance), 0.991990, 1e-6)
+
+	calc := &Calculator{}
+	when := calc.GetTimeFromJulianDate(NewJulianDate(2448724.5, 0), TimeScaleTT)
+	moon := calc.ComputeMoon(Observer{}, when)
+	// Meeus rounds the nutation in longitude to 0.004610 and ε to 23.440636
+	checkValue(t, "apparent λ", moon.Longitude, 133.167265, 1e-5)
+	checkValue(t, "α", moon.RightAscension, 134.688470, 2e-5)
+	checkValue(t, "δ", moon.Declination, 13.768368, 2e-5)
+	// Meeus uses a low-precision sun here
+	checkValue(t, "i", moon.PhaseAngle, 69.0756, 0.01)
+	checkValue(t, "k", moon.IlluminatedFraction, 0.6786, 1e-4)
+
+	// full moon of 2021 May 26 at 11:14 UTC and new moon of 2021 June 10 at 10:53 UTC
+	full := calc.ComputeMoon(Observer{}, time.Date(2021, time.May, 26, 11, 14, 0, 0, time.UTC))
+	if math.Abs(full.Phase - 180) > 0.02 || full.IlluminatedFraction < 0.99 {
+		t.Errorf("full moon has phase %g and illuminated fraction %g", full.Phase, full.IlluminatedFraction)
+	}
+	newMoon := calc.ComputeMoon(Observer{}, time.Date(2021, time.June, 10, 10, 53, 0, 0, time.UTC))
+	if math.Abs(limitDegrees180(newMoon.Phase)) > 0.02 || newMoon.IlluminatedFraction > 0.01 {
+		t.Errorf("new moon has phase %g and illuminated fraction %g", newMoon.Phase, newMoon.IlluminatedFraction)
+	}
+
+	// the topocentric position differs from the geocentric by up to the parallax
+	obs := Observer{Latitude: 39.742476, Longitude: -105.1786, Elevation: 1830.14}
+	moon = calc.ComputeMoon(obs, time.Date(2021, time.May, 26, 11, 14, 0, 0, time.UTC))
+	geo := GetDirection(90 - moon.LocalHourAngle, moon.Declination)
+	topo := GetDirection(90 - moon.TopocentricLocalHourAngle, moon.TopocentricDeclination)
+	if shift := rad2deg(geo.Sub(topo).Norm()); shift < 0.1 || shift > moon.EquatorialHorizontalParallax {
+		t.Errorf("topocentric shift of %g degrees, parallax %g", shift, moon.EquatorialHorizontalParallax)
+	}
+
+	// a month of moon events
+	den, _ := time.LoadLocation("America/Denver")
+	rises := 0
+	for d := 0; d < 30; d++ {
+		date := time.Date(2021, time.May, 1 + d, 0, 0, 0, 0, den)
+		events := calc.GetMoonEvents(obs, date, den)
+		if events.AlwaysUp || events.AlwaysDown {
+			t.Errorf("moon stays up or down at %g latitude on %s", obs.Latitude, date.Format("2006-01-02"))
+		}
+		if !events.Moonrise.IsZero() {
+			rises += 1
+			if events.Moonrise.Day() != date.Day() || events.Moonrise.Location() != den {
+				t.Errorf("moonrise for %s at %s", date.Format("2006-01-02"), events.Moonrise)
+			}
+			if e := calc.getMoonHorizonElevation(obs, events.Moonrise); math.Abs(e) > 1e-3 {
+				t.Errorf("moon is %g degrees from the horizon at moonrise %s", e, events.Moonrise)
+			}
+			if calc.getMoonHorizonElevation(obs, events.Moonrise.Add(time.Minute)) <= 0 {
+				t.Errorf("moon is not rising at %s", events.Moonrise)
+			}
+		}
+		if !events.Moonset.IsZero() {
+			if e := calc.getMoonHorizonElevation(obs, events.Moonset); math.Abs(e) > 1e-3 {
+				t.Errorf("moon is %g degrees from the horizon at moonset %s", e, events.Moonset)
+			}
+		}
+		if !events.Transit.IsZero() {
+			if h := limitDegrees180(calc.ComputeMoon(obs, events.Transit).TopocentricLocalHourAngle); math.Abs(h) > 1e-3 {
+				t.Errorf("moon hour angle is %g at transit %s", h, events.Transit)
+			}
+		}
+		// the full moon rises around sunset
+		if date.Day() == 25 {
+			sunset := GetSunEvents(obs.Latitude, obs.Longitude, obs.Elevation, date, den).Sunset
+			if d := events.Moonrise.Sub(sunset); d < -time.Hour || d > time.Hour {
+				t.Errorf("moonrise %s is far from sunset %s the evening before the full moon", events.Moonrise, sunset)
+			}
+		}
+	}
+	// the moon rises about 50 minutes later each day, so one day a month it does not rise
+	if rises < 28 || rises > 29 {
+		t.Errorf("moon rose %d times in 30 days", rises)
+	}
+
+	// the full moon of the polar night stays up at high northern latitudes
+	events := calc.GetMoonEvents(Observer{Latitude: 78, Longitude: 15}, time.Date(2021, time.December, 19, 0, 0, 0, 0, time.UTC), time.UTC)
+	if !events.AlwaysUp {
+		t.Errorf("moon at 78N on 2021-12-19: %+v", events)
+	}
+}
+
+func TestEclipse(t *testing.T) {
+	e, err := LoadBesselianElements("testdata/eclipse-2024-04-08.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValue(t, "T0", e.T0.Float(), 2460409.25, 1e-9)
+	checkValue(t, "x1", e.X[1], 0.5117116, 0)
+	checkValue(t, "mu1", e.Mu[1], 15.004084, 0)
+
+	checkValue(t, "equal disks", GetDiskObscuration(1, 1, 0), 1, 1e-12)
+	checkValue(t, "half overlap", GetDiskObscuration(1, 1, 1), (2 * math.Pi / 3 - math.Sqrt(3) / 2) / math.Pi, 1e-12)
+	checkValue(t, "annulus", GetDiskObscuration(1, 0.9, 0.05), 0.81, 1e-12)
+	checkValue(t, "apart", GetDiskObscuration(1, 1, 2), 0, 0)
+
+	calc := &Calculator{DeltaT: EspenakMeeusDeltaT}
+	computed := calc.ComputeBesselianElements(time.Date(2024, time.April, 8, 18, 10, 0, 0, time.UTC))
+	if computed.T0 != e.T0 {
+		t.Errorf("computed elements for T0 %v, expected %v", computed.T0, e.T0)
+	}
+	// the lunar theory places the shadow within about 20 km, 0.003 earth radii; here it is 4 km
+	for i, pair := range [][2][4]float64{{computed.X, e.X}, {computed.Y, e.Y}, {computed.L1, e.L1}, {computed.L2, e.L2}} {
+		for k, tol := range []float64{1e-3, 3e-5, 1e-6} {
+			if d := pair[0][k] - pair[1][k]; math.Abs(d) > tol {
+				t.Errorf("computed element %d coefficient %d is off by %g", i, k, d)
+			}
+		}
+	}
+	checkValue(t, "computed d", computed.D[0], e.D[0], 1e-3)
+	checkValue(t, "computed mu", computed.Mu[0], e.Mu[0], 1e-3)
+	checkValue(t, "computed tanf1", computed.TanF1, e.TanF1, 1e-7)
+	checkValue(t, "computed tanf2", computed.TanF2, e.TanF2, 1e-7)
+
+	// NASA local circumstances for Dallas, Texas
+	dallas := Observer{Latitude: 32.7767, Longitude: -96.7970, Elevation: 131}
+	contacts := []time.Time{
+		time.Date(2024, time.April, 8, 17, 23, 20, 0, time.UTC),
+		time.Date(2024, time.April, 8, 18, 40, 43, 0, time.UTC),
+		time.Date(2024, time.April, 8, 18, 44, 35, 0, time.UTC),
+		time.Date(2024, time.April, 8, 20, 2, 48, 0, time.UTC),
+	}
+	for _, elements := range []*BesselianElements{e, computed} {
+		local := calc.GetLocalEclipse(elements, dallas)
+		if local.Type != TotalEclipse || local.Obscuration != 1 || local.Magnitude < 1.01 || local.Magnitude > 1.02 {
+			t.Errorf("Dallas has a %s eclipse of magnitude %g and obscuration %g", local.Type, local.Magnitude, local.Obscuration)
+		}
+		for i, when := range []time.Time{local.C1, local.C2, local.C3, local.C4} {
+			if d := when.Sub(contacts[i]); d < -15 * time.Second || d > 15 * time.Second {
+				t.Errorf("Dallas C%d at %s, expected %s", i + 1, when, contacts[i])
+			}
+		}
+		if local.SunAltitude < 60 || local.Maximum.Before(local.C2) || local.Maximum.After(local.C3) {
+			t.Errorf("Dallas maximum at %s with the sun at %g", local.Maximum, local.SunAltitude)
+		}
+	}
+
+	// New York sees a deep partial eclipse, and Sydney sees nothing, at night
+	nyc := calc.GetLocalEclipse(e, Observer{Latitude: 40.7128, Longitude: -74.0060})
+	if nyc.Type != PartialEclipse || !nyc.C2.IsZero() || !nyc.C3.IsZero() {
+		t.Errorf("New York has a %s eclipse: %+v", nyc.Type, nyc)
+	}
+	checkValue(t, "New York magnitude", nyc.Magnitude, 0.91, 0.01)
+	checkValue(t, "New York obscuration", nyc.Obscuration, 0.897, 0.01)
+	if sydney := calc.GetLocalEclipse(e, Observer{Latitude: -33.8688, Longitude: 151.2093}); sydney.Type != NoEclipse || !sydney.C1.IsZero() {
+		t.Errorf("Sydney has a %s eclipse", sydney.Type)
+	}
+	if paris := calc.GetLocalEclipse(e, Observer{Latitude: 48.8566, Longitude: 2.3522}); paris.Type != NoEclipse {
+		t.Errorf("Paris has a %s eclipse", paris.Type)
+	}
+
+	// the obscuration through the day
+	local := calc.GetLocalEclipse(e, dallas)
+	times := make([]time.Time, 360)
+	for i := range times {
+		times[i] = time.Date(2024, time.April, 8, 16, i, 0, 0, time.UTC)
+	}
+	out := make([]float64, len(times))
+	if err := calc.GetEclipseObscurations(e, dallas, times, out); err != nil {
+		t.Fatal(err)
+	}
+	for i, when := range times {
+		if single := calc.GetEclipseObscuration(e, dallas, when); single != out[i] {
+			t.Errorf("obscuration at %s is %g, batch %g", when, single, out[i])
+		}
+		partial := when.After(local.C1) && when.Before(local.C4)
+		total := when.After(local.C2) && when.Before(local.C3)
+		if (out[i] > 0) != partial || (out[i] == 1) != total {
+			t.Errorf("obscuration at %s is %g", when, out[i])
+		}
+		if i > 0 && math.Abs(out[i] - out[i - 1]) > 0.03 {
+			t.Errorf("obscuration jumps from %g to %g at %s", out[i - 1], out[i], when)
+		}
+	}
+	checkValue(t, "obscuration at maximum", calc.GetEclipseObscuration(e, dallas, local.Maximum), 1, 0)
+	if err := calc.GetEclipseObscurations(e, dallas, times, out[1:]); err != ErrBatchLength {
+		t.Errorf("expected ErrBatchLength, got %v", err)
+	}
+
+	bad := map[string]error{
+		"t0 2024-04-08\n": ErrBesselianFormat,
+		"x 1 2 3 4 5\n": ErrBesselianFormat,
+		"x 1 a\n": ErrBesselianFormat,
+		"x 1\nx 2\n": ErrBesselianFormat,
+		"tanf1 1 2\n": ErrBesselianFormat,
+		"f 1\n": ErrBesselianFormat,
+		"# no elements\n": ErrBesselianMissing,
+	}
+	for text, expected := range bad {
+		if _, err := ParseBesselianElements(strings.NewReader(text)); !errors.Is(err, expected) {
+			t.Errorf("parsing %q gave %v, expected %v", text, err, expected)
+		}
+	}
+}